@@ -0,0 +1,25 @@
+// Command gochess-uci runs gochess as a UCI engine over stdin and stdout, to be used by chess GUIs.
+// It doesn't import the frontend, so it builds without OpenGL.
+package main
+
+import (
+	"bufio"
+	"os"
+
+	"github.com/OFFTKP/gochess/core"
+)
+
+func main() {
+	out := bufio.NewWriter(os.Stdout)
+	uci := core.NewUCI(out)
+	scanner := bufio.NewScanner(os.Stdin)
+	// position commands of long games don't fit in the default buffer
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if uci.ParseCommand(scanner.Text()) {
+			return
+		}
+	}
+	// the GUI closed the pipe without quit
+	uci.ParseCommand("quit")
+}
@@ -0,0 +1,65 @@
+package core
+
+import "math/bits"
+
+// Attack maps, see https://www.chessprogramming.org/Square_Attacked_By
+// Squares are numbered from a1 (0) to h8 (63) and colors are c_White or c_Black
+
+// Returns the pieces of both colors that attack the square, given the occupancy.
+// Pieces can be removed from the occupancy to look through them, like a king stepping back along a check
+func (board *Board) AttackersTo(square int, occupancy uint64) uint64 {
+	bit := uint64(1) << square
+	knights := board.whiteKnights | board.blackKnights
+	kings := board.whiteKing | board.blackKing
+	rooksQueens := board.whiteRooks | board.blackRooks | board.whiteQueens | board.blackQueens
+	bishopsQueens := board.whiteBishops | board.blackBishops | board.whiteQueens | board.blackQueens
+	return (knightMovesPerSquare[square] & knights) |
+		(kingMovesPerSquare[square] & kings) |
+		(rookAttacks(square, occupancy) & rooksQueens) |
+		(bishopAttacks(square, occupancy) & bishopsQueens) |
+		(pawnAttacks(c_Black, bit) & board.whitePawns) |
+		(pawnAttacks(c_White, bit) & board.blackPawns)
+}
+
+// Whether any piece of the color attacks the square in the current position
+func (board *Board) IsSquareAttacked(square int, byColor int) bool {
+	return (board.AttackersTo(square, ^board.emptySquares) & *board.ColorBBmap[byColor]) != 0
+}
+
+// Returns the enemy pieces giving check to the side to move
+func (board *Board) Checkers() uint64 {
+	king := *board.PieceBBmap[board.nextColor+p_King]
+	if king == 0 {
+		return 0
+	}
+	kingSquare := bits.TrailingZeros64(king)
+	return board.AttackersTo(kingSquare, ^board.emptySquares) & *board.ColorBBmap[c_Black-board.nextColor]
+}
+
+func (board *Board) InCheck() bool {
+	return board.Checkers() != 0
+}
+
+// Returns the pieces of the color that are pinned to their own king by an enemy slider
+func (board *Board) PinnedPieces(color int) uint64 {
+	king := *board.PieceBBmap[color+p_King]
+	if king == 0 {
+		return 0
+	}
+	enemy := c_Black - color
+	kingSquare := bits.TrailingZeros64(king)
+	occupancy := ^board.emptySquares
+	enemyQueens := *board.PieceBBmap[enemy+p_Queen]
+	snipers := (rookAttacks(kingSquare, 0) & (*board.PieceBBmap[enemy+p_Rook] | enemyQueens)) |
+		(bishopAttacks(kingSquare, 0) & (*board.PieceBBmap[enemy+p_Bishop] | enemyQueens))
+	var pinned uint64
+	for snipers != 0 {
+		sniper := bits.TrailingZeros64(snipers)
+		snipers &= snipers - 1
+		blockers := betweenSquares[kingSquare][sniper] & occupancy
+		if bits.OnesCount64(blockers) == 1 {
+			pinned |= blockers & *board.ColorBBmap[color]
+		}
+	}
+	return pinned
+}
@@ -0,0 +1,114 @@
+package core
+
+import "testing"
+
+// builds a bitboard from squares in algebraic notation
+func squaresToBitboard(squares ...string) uint64 {
+	var bb uint64
+	for _, square := range squares {
+		bb |= uint64(1) << AlgebraicToUint8(square)
+	}
+	return bb
+}
+
+func TestAttackersTo(t *testing.T) {
+	type testCase struct {
+		fen      string
+		square   string
+		expected uint64
+	}
+	testCases := []testCase{
+		{startPositionFen, "f3", squaresToBitboard("e2", "g2", "g1")},
+		{startPositionFen, "f6", squaresToBitboard("e7", "g7", "g8")},
+		{startPositionFen, "e4", 0},
+		// sliders are blocked by the first piece on the ray, of either color
+		{"4k3/8/8/8/4p3/8/4R3/4K3 w - - 0 1", "e4", squaresToBitboard("e2")},
+		{"4k3/8/8/8/4p3/8/4R3/4K3 w - - 0 1", "e5", 0},
+		// pawns attack diagonally forward, also across the edge files without wrapping
+		{"4k3/8/8/1p6/P7/8/8/4K3 w - - 0 1", "b5", squaresToBitboard("a4")},
+		{"4k3/8/8/1p6/P7/8/8/4K3 w - - 0 1", "a4", squaresToBitboard("b5")},
+		{"4k3/8/8/8/7P/8/8/4K3 w - - 0 1", "a5", 0},
+		{"4k3/8/8/8/8/8/8/Q3K3 w - - 0 1", "h8", squaresToBitboard("a1")},
+		{"4k3/8/8/8/8/8/8/Q3K3 w - - 0 1", "d2", squaresToBitboard("e1")},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		attackers := board.AttackersTo(int(AlgebraicToUint8(test.square)), ^board.emptySquares)
+		if attackers != test.expected {
+			t.Errorf("%s attackers of %s\nExpected:%064b\n     Got:%064b", test.fen, test.square, test.expected, attackers)
+		}
+	}
+
+	// the occupancy decides what the sliders see through
+	var board Board
+	board.LoadFen("4k3/8/8/8/4p3/8/4R3/4K3 w - - 0 1")
+	occupancy := ^board.emptySquares &^ squaresToBitboard("e4")
+	if attackers := board.AttackersTo(int(AlgebraicToUint8("e5")), occupancy); attackers != squaresToBitboard("e2") {
+		t.Errorf("Rook doesn't see through the removed pawn: %064b", attackers)
+	}
+}
+
+func TestIsSquareAttacked(t *testing.T) {
+	var board Board
+	board.LoadFen("4k3/8/8/3n4/8/8/8/R3K3 w - - 0 1")
+	type testCase struct {
+		square  string
+		byColor int
+		attacks bool
+	}
+	testCases := []testCase{
+		{"a8", c_White, true},
+		{"a8", c_Black, false},
+		{"e3", c_Black, true},
+		{"e3", c_White, false},
+		{"d7", c_Black, true},
+		{"d5", c_White, false},
+		{"f2", c_White, true},
+	}
+	for _, test := range testCases {
+		if attacked := board.IsSquareAttacked(int(AlgebraicToUint8(test.square)), test.byColor); attacked != test.attacks {
+			t.Errorf("%s attacked by color %d: expected %t", test.square, test.byColor, test.attacks)
+		}
+	}
+}
+
+func TestChecksAndPins(t *testing.T) {
+	type testCase struct {
+		fen      string
+		checkers uint64
+		pinned   uint64
+	}
+	testCases := []testCase{
+		{startPositionFen, 0, 0},
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", squaresToBitboard("h4"), 0},
+		// double check by a knight and a rook
+		{"4r1k1/8/8/8/8/3n4/8/4K3 w - - 0 1", squaresToBitboard("e8", "d3"), 0},
+		// the knight is pinned by the bishop, the rook isn't between the king and the enemy rook
+		{"4r1k1/8/8/7b/8/4R3/4N3/3K4 w - - 0 1", 0, squaresToBitboard("e2")},
+		{"4r1k1/8/8/8/b7/8/2N5/3K4 w - - 0 1", 0, squaresToBitboard("c2")},
+		{"4r1k1/8/8/8/b7/4R3/2N5/4K3 w - - 0 1", 0, squaresToBitboard("e3")},
+		// pieces of the other color don't count as pinned
+		{"4r1k1/8/8/8/8/4n3/8/4K3 w - - 0 1", 0, 0},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		if checkers := board.Checkers(); checkers != test.checkers {
+			t.Errorf("%s checkers\nExpected:%064b\n     Got:%064b", test.fen, test.checkers, checkers)
+		}
+		if board.InCheck() != (test.checkers != 0) {
+			t.Errorf("%s: bad in check %t", test.fen, board.InCheck())
+		}
+		if pinned := board.PinnedPieces(board.nextColor); pinned != test.pinned {
+			t.Errorf("%s pinned pieces\nExpected:%064b\n     Got:%064b", test.fen, test.pinned, pinned)
+		}
+	}
+
+	// boards without kings have no checks or pins
+	var board Board
+	board.LoadFen("8/8/8/8/8/8/8/R7 w - - 0 1")
+	if board.InCheck() || board.PinnedPieces(c_White) != 0 {
+		t.Error("Check or pin on a board without kings")
+	}
+}
@@ -9,8 +9,13 @@ const (
 	notAFile uint64 = 0xfefefefefefefefe
 	notHFile uint64 = 0x7f7f7f7f7f7f7f7f
 
+	rank3 uint64 = 0x0000000000FF0000
 	rank4 uint64 = 0x00000000FF000000
 	rank5 uint64 = 0x000000FF00000000
+	rank6 uint64 = 0x0000FF0000000000
+
+	lightSquares uint64 = 0x55AA55AA55AA55AA
+	darkSquares  uint64 = 0xAA55AA55AA55AA55
 )
 
 func soutOne(b uint64) uint64 {
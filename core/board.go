@@ -3,11 +3,10 @@ package core
 import (
 	"math"
 	"math/bits"
-	"math/rand"
-	"time"
+	"sync"
 )
 
-var oneTimeInitialized bool = false
+var oneTimeInitOnce sync.Once
 
 const (
 	p_Pawn = iota
@@ -23,6 +22,8 @@ const (
 	c_Black = 6
 )
 
+const startPositionFen = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
 var piecePower [6]int = [6]int{100, 300, 300, 500, 900, math.MaxInt32}
 
 type Board struct {
@@ -40,28 +41,10 @@ type Board struct {
 	whiteQueens  uint64
 	whiteKing    uint64
 
-	blackPawnHashMap   [64]uint64
-	blackKnightHashMap [64]uint64
-	blackBishopHashMap [64]uint64
-	blackRookHashMap   [64]uint64
-	blackQueenHashMap  [64]uint64
-	blackKingHashMap   [64]uint64
-
-	whitePawnHashMap   [64]uint64
-	whiteKnightHashMap [64]uint64
-	whiteBishopHashMap [64]uint64
-	whiteRookHashMap   [64]uint64
-	whiteQueenHashMap  [64]uint64
-	whiteKingHashMap   [64]uint64
-	nextColorHashMap   [7]uint64  // 0 - white, 6 - black
-	castlingHashMap    [16]uint64 // 0000 KQkq bits for speed
-	enPassantHashMap   [8]uint64  // to indicate the file of the en passant square
-
 	// maps for all piece types (0-11) to avoid branching
-	PieceBBmap   [12]*uint64
-	ColorBBmap   [7]*uint64
-	PieceHashmap [12]*[64]uint64
-	zobristHash  uint64
+	PieceBBmap  [12]*uint64
+	ColorBBmap  [7]*uint64
+	zobristHash uint64
 
 	// general bitboards of all pieces together
 	whiteSquares uint64
@@ -70,23 +53,61 @@ type Board struct {
 
 	whiteKingsideCastle, whiteQueensideCastle,
 	blackKingsideCastle, blackQueensideCastle int
+	// starting squares of the castling rooks, which can stand on any file in Chess960
+	whiteKingsideRook, whiteQueensideRook,
+	blackKingsideRook, blackQueensideRook uint8
+	// castling moves are written as the king taking its own rook, as UCI expects for Chess960
+	chess960 bool
 
 	nextColor       int
 	enPassantSquare uint8
 	enPassantCol    uint8
 	halfmoveClock   int
 	fullmoveNumber  int
+
+	// states of the positions before each move played, to be able to unmake them
+	history []undoState
+
+	// allocated on the first search if not set
+	tt *TranspositionTable
+}
+
+// Chess960 only changes how castling moves are written, the castling rules of standard chess are a special case of it
+func (board *Board) SetChess960(enabled bool) {
+	board.chess960 = enabled
+}
+
+// Sets the transposition table used by the search, which may be shared between boards that don't search concurrently
+func (board *Board) SetTranspositionTable(tt *TranspositionTable) {
+	board.tt = tt
 }
 
 func oneTimeInit() {
-	rand.Seed(time.Now().UnixNano())
+	generateZobrist()
 	generateSliding()
+	generateMagics()
+	generateLines()
 }
 
 func (board *Board) init() {
-	if !oneTimeInitialized {
-		oneTimeInit()
+	oneTimeInitOnce.Do(oneTimeInit)
+	board.linkMaps()
+	for _, bb := range board.PieceBBmap {
+		*bb = 0
 	}
+	board.blackKingsideCastle = 0
+	board.blackQueensideCastle = 0
+	board.whiteKingsideCastle = 0
+	board.whiteQueensideCastle = 0
+	board.whiteKingsideRook, board.whiteQueensideRook = 7, 0
+	board.blackKingsideRook, board.blackQueensideRook = 63, 56
+	board.enPassantSquare = 0xFF
+	board.enPassantCol = 0
+	board.history = board.history[:0]
+}
+
+// points the maps to the bitboards of this board
+func (board *Board) linkMaps() {
 	board.PieceBBmap = [12]*uint64{
 		&board.whitePawns, &board.whiteKnights, &board.whiteBishops, &board.whiteRooks, &board.whiteQueens, &board.whiteKing,
 		&board.blackPawns, &board.blackKnights, &board.blackBishops, &board.blackRooks, &board.blackQueens, &board.blackKing,
@@ -94,60 +115,64 @@ func (board *Board) init() {
 	board.ColorBBmap = [7]*uint64{
 		&board.whiteSquares, nil, nil, nil, nil, nil, &board.blackSquares,
 	}
-	board.blackKingsideCastle = 0
-	board.blackQueensideCastle = 0
-	board.whiteKingsideCastle = 0
-	board.whiteQueensideCastle = 0
-	board.enPassantSquare = 0xFF
-	for i := 0; i < 64; i++ {
-		board.whitePawnHashMap[i] = rand.Uint64()
-		board.whiteKnightHashMap[i] = rand.Uint64()
-		board.whiteBishopHashMap[i] = rand.Uint64()
-		board.whiteRookHashMap[i] = rand.Uint64()
-		board.whiteQueenHashMap[i] = rand.Uint64()
-		board.whiteKingHashMap[i] = rand.Uint64()
-		board.blackPawnHashMap[i] = rand.Uint64()
-		board.blackKnightHashMap[i] = rand.Uint64()
-		board.blackBishopHashMap[i] = rand.Uint64()
-		board.blackRookHashMap[i] = rand.Uint64()
-		board.blackQueenHashMap[i] = rand.Uint64()
-		board.blackKingHashMap[i] = rand.Uint64()
-	}
-	board.nextColorHashMap[0] = rand.Uint64()
-	board.nextColorHashMap[6] = rand.Uint64()
-	for i := 0; i < 4; i++ {
-		board.castlingHashMap[i] = rand.Uint64()
-	}
-	for i := 0; i < 8; i++ {
-		board.enPassantHashMap[i] = rand.Uint64()
-	}
-	board.PieceHashmap = [12]*[64]uint64{
-		&board.whitePawnHashMap, &board.whiteKnightHashMap, &board.whiteBishopHashMap, &board.whiteRookHashMap, &board.whiteQueenHashMap, &board.whiteKingHashMap,
-		&board.blackPawnHashMap, &board.blackKnightHashMap, &board.blackBishopHashMap, &board.blackRookHashMap, &board.blackQueenHashMap, &board.blackKingHashMap,
-	}
 }
 
+// Returns an independent copy of the board, including its history, that shares the transposition table.
+// Boards can't be copied by value since the maps point into them
+func (board *Board) Clone() *Board {
+	clone := &Board{}
+	*clone = *board
+	clone.linkMaps()
+	clone.history = append([]undoState(nil), board.history...)
+	return clone
+}
+
+// Sets up the start position for a new game, forgetting the moves played and everything
+// the transposition table learned, so consecutive games don't influence each other
 func (board *Board) Reset() {
-	// TODO: implement what happens on ucinewgame?
+	board.LoadFen(startPositionFen)
+	if board.tt != nil {
+		board.tt.Clear()
+	}
+}
+
+func (board *Board) castlingIndex() int {
+	return board.whiteKingsideCastle<<3 | board.whiteQueensideCastle<<2 | board.blackKingsideCastle<<1 | board.blackQueensideCastle
+}
+
+// whether a pawn of the side to move attacks the en passant square, positions where it can't
+// capture are the same as those without en passant for repetitions and hashing
+func (board *Board) enPassantCapturable() bool {
+	if board.enPassantSquare == 0xFF {
+		return false
+	}
+	us := board.nextColor
+	return pawnAttacks(c_Black-us, uint64(1)<<board.enPassantSquare)&*board.PieceBBmap[us+p_Pawn] != 0
+}
+
+// hash of the castling rights, en passant file and side to move
+func (board *Board) stateZobrist() uint64 {
+	ret := nextColorHashMap[board.nextColor] ^ castlingHashMap[board.castlingIndex()]
+	if board.enPassantCapturable() {
+		ret ^= enPassantHashMap[board.enPassantCol]
+	}
+	return ret
 }
 
 func (board *Board) recalculateZobrist() {
 	board.zobristHash = 0
-	emptyCopy := board.emptySquares
-	for emptyCopy != 0 {
-		bit := bits.TrailingZeros64(emptyCopy)
+	occupiedCopy := ^board.emptySquares
+	for occupiedCopy != 0 {
+		bit := bits.TrailingZeros64(occupiedCopy)
 		for j := 0; j < 12; j++ {
 			if ((*board.PieceBBmap[j] >> bit) & 1) != 0 {
-				board.zobristHash ^= (*board.PieceHashmap[j])[bit]
+				board.zobristHash ^= pieceHashMap[j][bit]
 				break
 			}
 		}
-		emptyCopy ^= 1 << bit
+		occupiedCopy ^= 1 << bit
 	}
-	board.zobristHash ^= board.nextColorHashMap[board.nextColor]
-	castling := board.whiteKingsideCastle<<3 | board.whiteQueensideCastle<<2 | board.blackKingsideCastle<<1 | board.blackQueensideCastle
-	board.zobristHash ^= board.castlingHashMap[castling]
-	board.zobristHash ^= board.enPassantHashMap[board.enPassantCol]
+	board.zobristHash ^= board.stateZobrist()
 }
 
 // function that recalculates the occupying maps
@@ -158,46 +183,192 @@ func (board *Board) recalculateGeneralMaps() {
 	board.emptySquares = (^board.whiteSquares) & (^board.blackSquares)
 }
 
-// returns -1 if no piece is captured, or > -1 if a piece is captured and the index of
-// the corresponding bitboard
-func (board *Board) makeMove(bitboardIndex uint8, oldSquare, newSquare uint8) int {
-	var ret int = -1
-	oldBitCheck := uint64(1) << oldSquare
-	// remove the moving piece from hash
-	board.zobristHash ^= (*board.PieceHashmap[bitboardIndex])[oldSquare]
-	// remove the moving piece from bitboard
-	*board.PieceBBmap[bitboardIndex] &= ^oldBitCheck
-	// find if new square contains a piece thats being captured
-	// 0 meaning its not empty here
-	bitCheck := uint64(1) << newSquare
-	if (board.emptySquares & bitCheck) == 0 {
-		// find which piece it is
-		for ret = 0; ; /* no test */ ret++ {
-			if (*board.PieceBBmap[ret] & bitCheck) != 0 {
-				break
-			}
+// returns the index of the bitboard with a piece on square, or -1 if its empty
+func (board *Board) pieceAt(square uint8) int {
+	bitCheck := uint64(1) << square
+	if (board.emptySquares & bitCheck) != 0 {
+		return -1
+	}
+	for i := 0; i < 12; i++ {
+		if (*board.PieceBBmap[i] & bitCheck) != 0 {
+			return i
 		}
-		// remove the captured piece from hash
-		board.zobristHash ^= (*board.PieceHashmap[ret])[newSquare]
-		// remove the captured piece from bitboard
-		*board.PieceBBmap[ret] &= ^bitCheck
 	}
-	board.zobristHash ^= (*board.PieceHashmap[bitboardIndex])[newSquare]
-	*board.PieceBBmap[bitboardIndex] |= uint64(1) << newSquare
+	return -1
+}
+
+// the irreversible parts of the board state, needed to unmake a move
+type undoState struct {
+	move     Move
+	captured int
+	whiteKingsideCastle, whiteQueensideCastle,
+	blackKingsideCastle, blackQueensideCastle int
+	enPassantSquare uint8
+	enPassantCol    uint8
+	halfmoveClock   int
+	zobristHash     uint64
+}
+
+func (board *Board) movePiece(piece int, from, to uint8) {
+	board.removePiece(piece, from)
+	board.putPiece(piece, to)
+}
+
+func (board *Board) removePiece(piece int, square uint8) {
+	board.zobristHash ^= pieceHashMap[piece][square]
+	*board.PieceBBmap[piece] &= ^(uint64(1) << square)
+}
+
+func (board *Board) putPiece(piece int, square uint8) {
+	board.zobristHash ^= pieceHashMap[piece][square]
+	*board.PieceBBmap[piece] |= uint64(1) << square
+}
+
+// castling rights are lost when the king moves or a rook leaves (or is captured on) its starting square
+func (board *Board) updateCastlingRights(piece int, from, to uint8) {
+	switch piece {
+	case c_White + p_King:
+		board.whiteKingsideCastle = 0
+		board.whiteQueensideCastle = 0
+	case c_Black + p_King:
+		board.blackKingsideCastle = 0
+		board.blackQueensideCastle = 0
+	}
+	for _, square := range [2]uint8{from, to} {
+		switch square {
+		case board.whiteKingsideRook:
+			board.whiteKingsideCastle = 0
+		case board.whiteQueensideRook:
+			board.whiteQueensideCastle = 0
+		case board.blackKingsideRook:
+			board.blackKingsideCastle = 0
+		case board.blackQueensideRook:
+			board.blackQueensideCastle = 0
+		}
+	}
+}
+
+// returns the squares the rook castles from and to, the king castles to the g or c file
+// and the rook lands next to it on the f or d file
+func (board *Board) castlingRookSquares(color int, m Move) (uint8, uint8) {
+	if m.flags() == m_KingCastle {
+		if color == c_White {
+			return board.whiteKingsideRook, m.To() - 1
+		}
+		return board.blackKingsideRook, m.To() - 1
+	}
+	if color == c_White {
+		return board.whiteQueensideRook, m.To() + 1
+	}
+	return board.blackQueensideRook, m.To() + 1
+}
+
+// Plays a legal move, the previous state is kept in the history to be restored by UnmakeMove
+func (board *Board) MakeMove(m Move) {
+	state := undoState{
+		move:                 m,
+		captured:             -1,
+		whiteKingsideCastle:  board.whiteKingsideCastle,
+		whiteQueensideCastle: board.whiteQueensideCastle,
+		blackKingsideCastle:  board.blackKingsideCastle,
+		blackQueensideCastle: board.blackQueensideCastle,
+		enPassantSquare:      board.enPassantSquare,
+		enPassantCol:         board.enPassantCol,
+		halfmoveClock:        board.halfmoveClock,
+		zobristHash:          board.zobristHash,
+	}
+	us := board.nextColor
+	from, to := m.From(), m.To()
+	piece := board.pieceAt(from)
+	flags := m.flags()
+	board.halfmoveClock++
+	// the castling, en passant and side to move keys are swapped for the new ones at the end
+	board.zobristHash ^= board.stateZobrist()
+	if flags == m_EnPassant {
+		state.captured = c_Black - us + p_Pawn
+		if us == c_White {
+			board.removePiece(state.captured, to-8)
+		} else {
+			board.removePiece(state.captured, to+8)
+		}
+	} else if m.IsCapture() {
+		state.captured = board.pieceAt(to)
+		board.removePiece(state.captured, to)
+	}
+	switch {
+	case m.IsCastle():
+		// in Chess960 the king and the rook may land on each other's squares, so both are lifted first
+		rookFrom, rookTo := board.castlingRookSquares(us, m)
+		board.removePiece(piece, from)
+		board.removePiece(us+p_Rook, rookFrom)
+		board.putPiece(piece, to)
+		board.putPiece(us+p_Rook, rookTo)
+	case m.IsPromotion():
+		board.removePiece(piece, from)
+		board.putPiece(us+m.PromotionPiece(), to)
+	default:
+		board.movePiece(piece, from, to)
+	}
+	if state.captured != -1 || piece == us+p_Pawn {
+		board.halfmoveClock = 0
+	}
+	board.updateCastlingRights(piece, from, to)
+	board.enPassantSquare = 0xFF
+	board.enPassantCol = 0
+	if flags == m_DoublePawnPush {
+		board.enPassantSquare = (from + to) / 2
+		board.enPassantCol = board.enPassantSquare & 0b111
+	}
+	if us == c_Black {
+		board.fullmoveNumber++
+	}
+	board.nextColor = c_Black - us
+	board.zobristHash ^= board.stateZobrist()
 	board.recalculateGeneralMaps()
-	return ret
+	board.history = append(board.history, state)
 }
 
-func (board *Board) unmakeMove(oldCapture int, bitboardIndex uint8, oldSquare, newSquare uint8) {
-	board.zobristHash ^= (*board.PieceHashmap[bitboardIndex])[newSquare]
-	bitCheck := uint64(1) << newSquare
-	*board.PieceBBmap[bitboardIndex] &= ^bitCheck
-	if oldCapture != -1 {
-		board.zobristHash ^= (*board.PieceHashmap[oldCapture])[newSquare]
-		*board.PieceBBmap[oldCapture] |= bitCheck
+// Takes back the last move played with MakeMove
+func (board *Board) UnmakeMove() {
+	state := board.history[len(board.history)-1]
+	board.history = board.history[:len(board.history)-1]
+	m := state.move
+	us := c_Black - board.nextColor
+	board.nextColor = us
+	if us == c_Black {
+		board.fullmoveNumber--
+	}
+	from, to := m.From(), m.To()
+	flags := m.flags()
+	switch {
+	case m.IsCastle():
+		rookFrom, rookTo := board.castlingRookSquares(us, m)
+		board.removePiece(us+p_King, to)
+		board.removePiece(us+p_Rook, rookTo)
+		board.putPiece(us+p_King, from)
+		board.putPiece(us+p_Rook, rookFrom)
+	case m.IsPromotion():
+		board.removePiece(us+m.PromotionPiece(), to)
+		board.putPiece(us+p_Pawn, from)
+	default:
+		board.movePiece(board.pieceAt(to), to, from)
+	}
+	if flags == m_EnPassant {
+		if us == c_White {
+			board.putPiece(state.captured, to-8)
+		} else {
+			board.putPiece(state.captured, to+8)
+		}
+	} else if state.captured != -1 {
+		board.putPiece(state.captured, to)
 	}
-	oldBitCheck := uint64(1) << oldSquare
-	board.zobristHash ^= (*board.PieceHashmap[bitboardIndex])[oldSquare]
-	*board.PieceBBmap[bitboardIndex] |= oldBitCheck
+	board.whiteKingsideCastle = state.whiteKingsideCastle
+	board.whiteQueensideCastle = state.whiteQueensideCastle
+	board.blackKingsideCastle = state.blackKingsideCastle
+	board.blackQueensideCastle = state.blackQueensideCastle
+	board.enPassantSquare = state.enPassantSquare
+	board.enPassantCol = state.enPassantCol
+	board.halfmoveClock = state.halfmoveClock
+	board.zobristHash = state.zobristHash
 	board.recalculateGeneralMaps()
 }
@@ -0,0 +1,75 @@
+package core
+
+import "math/bits"
+
+// Draws by repetition, by the fifty-move rule and by insufficient material, see https://www.chessprogramming.org/Repetitions
+// Positions are compared by their zobrist hash, which includes the side to move, castling rights and
+// the en passant file when a pawn is able to capture.
+// Captures and pawn moves can't be taken back, so only the positions since the last one are looked at
+
+// the number of moves in the history since the last capture or pawn move,
+// the clock may come from a fen with fewer moves played
+func (board *Board) reversibleMoves() int {
+	if board.halfmoveClock < len(board.history) {
+		return board.halfmoveClock
+	}
+	return len(board.history)
+}
+
+// Returns how many times the current position occurred in the game, counting the current one
+func (board *Board) RepetitionCount() int {
+	count := 1
+	// the same side is to move every second position, and it takes four moves to come back at the earliest
+	for i := 4; i <= board.reversibleMoves(); i += 2 {
+		if board.history[len(board.history)-i].zobristHash == board.zobristHash {
+			count++
+		}
+	}
+	return count
+}
+
+func (board *Board) IsThreefoldRepetition() bool {
+	return board.RepetitionCount() >= 3
+}
+
+// Whether fifty moves were played by each side without a capture or pawn move,
+// unless the last of them gave checkmate
+func (board *Board) IsFiftyMoveDraw() bool {
+	if board.halfmoveClock < 100 {
+		return false
+	}
+	return !board.InCheck() || len(board.GenerateLegalMoves()) > 0
+}
+
+// whether the search should score the position as a draw. A position repeated inside the search
+// tree can be repeated again, so once is enough there, positions played before the search
+// need to occur twice before like in the game
+func (board *Board) isRepetition(searchPly int) bool {
+	count := 0
+	for i := 4; i <= board.reversibleMoves(); i += 2 {
+		if board.history[len(board.history)-i].zobristHash == board.zobristHash {
+			if i <= searchPly {
+				return true
+			}
+			count++
+			if count == 2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether neither side can checkmate with any series of legal moves: bare kings, a single minor piece,
+// or only bishops that all stand on squares of the same color
+func (board *Board) IsInsufficientMaterial() bool {
+	if board.whitePawns|board.blackPawns|board.whiteRooks|board.blackRooks|board.whiteQueens|board.blackQueens != 0 {
+		return false
+	}
+	knights := board.whiteKnights | board.blackKnights
+	bishops := board.whiteBishops | board.blackBishops
+	if knights == 0 {
+		return bishops&lightSquares == 0 || bishops&darkSquares == 0
+	}
+	return bishops == 0 && bits.OnesCount64(knights) == 1
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRepetition(t *testing.T) {
+	type testCase struct {
+		moves    string
+		expected int
+	}
+	testCases := []testCase{
+		{"", 1},
+		{"g1f3 g8f6 f3g1", 1},
+		{"g1f3 g8f6 f3g1 f6g8", 2},
+		{"g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", 3},
+		// the same position with the other side to move isn't a repetition
+		{"g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8 g1f3", 3},
+		// positions before a pawn move can't come back
+		{"g1f3 g8f6 f3g1 f6g8 a2a3 a7a6 g1f3 g8f6 f3g1 f6g8", 2},
+		// a double push without a pawn next to it doesn't make the position different
+		{"e2e4 g8f6 g1f3 f6g8 f3g1 g8f6 g1f3 f6g8 f3g1", 3},
+		{"e2e4 d7d5 e4e5 f7f5 g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1 f6g8", 2},
+		// nor before a change of castling rights
+		{"g1f3 g8f6 h1g1 f6g8 g1h1 g8f6 f3g1 f6g8", 1},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(startPositionFen)
+		playMoves(t, &board, strings.Fields(test.moves))
+		if count := board.RepetitionCount(); count != test.expected {
+			t.Errorf("After %s: expected the position %d times, got %d", test.moves, test.expected, count)
+		}
+		if board.IsThreefoldRepetition() != (test.expected >= 3) {
+			t.Errorf("After %s: bad threefold repetition %t", test.moves, board.IsThreefoldRepetition())
+		}
+	}
+}
+
+func TestFiftyMoveDraw(t *testing.T) {
+	type testCase struct {
+		fen      string
+		expected bool
+	}
+	testCases := []testCase{
+		{"8/8/4k3/8/8/3K4/8/7R w - - 99 80", false},
+		{"8/8/4k3/8/8/3K4/8/7R w - - 100 80", true},
+		// checkmate on the last move takes precedence
+		{"R5k1/5ppp/8/8/8/8/8/6K1 b - - 100 80", false},
+		{"6k1/5ppp/8/8/8/8/8/R5K1 b - - 100 80", true},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		if board.IsFiftyMoveDraw() != test.expected {
+			t.Errorf("%s: expected fifty-move draw %t", test.fen, test.expected)
+		}
+	}
+
+	var board Board
+	board.LoadFen("8/8/4k3/8/8/3K4/P7/7R w - - 99 80")
+	playMoves(t, &board, strings.Fields("h1h2"))
+	if !board.IsFiftyMoveDraw() {
+		t.Error("Fifty-move rule not reached by the 100th move")
+	}
+	board.UnmakeMove()
+	playMoves(t, &board, strings.Fields("a2a3"))
+	if board.IsFiftyMoveDraw() {
+		t.Error("Fifty-move rule reached after a pawn move")
+	}
+}
+
+func TestSearchDraws(t *testing.T) {
+	// black is a queen down and can repeat the start position a third time
+	var board Board
+	board.LoadFen("rnb1kbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	playMoves(t, &board, strings.Fields("g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1"))
+	result := NewSearcher(&board).Search(3)
+	if result.Score != 0 || result.BestMove.String() != "f6g8" {
+		t.Errorf("Expected the draw by repetition f6g8 with score 0, got %s with %d", result.BestMove, result.Score)
+	}
+
+	// the rook is worth nothing once the fifty moves are up
+	board.LoadFen("8/8/4k3/8/8/3K4/8/7R w - - 99 80")
+	result = NewSearcher(&board).Search(2)
+	if result.Score != 0 {
+		t.Errorf("Expected a draw by the fifty-move rule, got %d", result.Score)
+	}
+}
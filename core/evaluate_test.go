@@ -43,13 +43,17 @@ func TestTrickyEvaluations(t *testing.T) {
 		// Test cases with fen, depth to test and minimum (or maximum) eval
 		// -inf or inf means black or white checkmate accordingly
 
-		// Blackburne Shilling Gambit accepted
-		{"r1bqkbnr/pppp1ppp/8/4N3/2BnP3/8/PPPP1PPP/RNBQK2R b KQkq - 0 4", 5, -50},
+		// Blackburne Shilling Gambit accepted, after 4...Qg5 5.Bxf7+ Ke7 6.Bxg8 Rxg8 white is two pawns up
+		// and the knight on e5 only falls after the horizon of shallower searches
+		{"r1bqkbnr/pppp1ppp/8/4N3/2BnP3/8/PPPP1PPP/RNBQK2R b KQkq - 0 4", 8, -50},
 		// Légall Mate
 		{"r2qkbnr/ppp2ppp/2np4/4N3/2B1P3/2N4P/PPPP1PP1/R1BbK2R w KQkq - 0 6", 3, math.MaxInt32},
 	}
 	var wg sync.WaitGroup
 	for _, test := range testCases {
+		if testing.Short() && test.depth > 5 {
+			continue
+		}
 		wg.Add(1)
 		go func(test testCase) {
 			defer wg.Done()
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"math/bits"
 	"strconv"
 	"strings"
 	"unicode"
@@ -72,18 +73,6 @@ func (board *Board) LoadFen(fen string) (bool, string) {
 	} else {
 		board.nextColor = c_White
 	}
-	for _, ch := range fenCastling {
-		switch ch {
-		case 'k':
-			board.blackKingsideCastle = 1
-		case 'q':
-			board.blackQueensideCastle = 1
-		case 'K':
-			board.whiteKingsideCastle = 1
-		case 'Q':
-			board.whiteQueensideCastle = 1
-		}
-	}
 	if len(fenSplit[3]) == 2 {
 		board.enPassantSquare = AlgebraicToUint8(fenSplit[3])
 		board.enPassantCol = board.enPassantSquare & 0b111
@@ -141,15 +130,82 @@ func (board *Board) LoadFen(fen string) (bool, string) {
 			y -= 8
 		}
 	}
-	if x != 8 && y != 0 {
+	if x != 8 || y != 0 {
 		return false, "Not enough or too many pieces in FEN"
 	}
+	board.loadCastlingRights(fenCastling)
 	board.recalculateGeneralMaps()
 	board.recalculateZobrist()
 	return true, ""
 }
 
+// Reads standard, Shredder-FEN and X-FEN castling rights, see https://en.wikipedia.org/wiki/X-FEN
+// KQkq stand for the outermost rook on that side of the king and file letters (HAha) for the rook on that file
+func (board *Board) loadCastlingRights(field string) {
+	for _, ch := range field {
+		color := c_White
+		backRank := uint8(0)
+		if unicode.IsLower(ch) {
+			color = c_Black
+			backRank = 56
+		}
+		rooks := *board.PieceBBmap[color+p_Rook]
+		// the king is assumed to be on the e file when it's not on its back rank
+		kingFile := uint8(4)
+		if king := *board.PieceBBmap[color+p_King] & (rank1 << backRank); king != 0 {
+			kingFile = uint8(bits.TrailingZeros64(king)) & 7
+		}
+		hasRook := func(file uint8) bool {
+			return (rooks & (uint64(1) << (backRank + file))) != 0
+		}
+		var file uint8
+		var kingside bool
+		switch lower := unicode.ToLower(ch); {
+		case lower == 'k':
+			kingside = true
+			for file = 7; file > kingFile && !hasRook(file); file-- {
+			}
+			if file == kingFile {
+				file = 7
+			}
+		case lower == 'q':
+			for file = 0; file < kingFile && !hasRook(file); file++ {
+			}
+			if file == kingFile {
+				file = 0
+			}
+		case lower >= 'a' && lower <= 'h':
+			file = uint8(lower - 'a')
+			kingside = file > kingFile
+		default:
+			continue
+		}
+		rook := backRank + file
+		switch {
+		case color == c_White && kingside:
+			board.whiteKingsideCastle, board.whiteKingsideRook = 1, rook
+		case color == c_White:
+			board.whiteQueensideCastle, board.whiteQueensideRook = 1, rook
+		case kingside:
+			board.blackKingsideCastle, board.blackKingsideRook = 1, rook
+		default:
+			board.blackQueensideCastle, board.blackQueensideRook = 1, rook
+		}
+	}
+}
+
+// Returns the position as a FEN, with X-FEN castling rights in Chess960 positions
+// which are the same as the standard ones unless there's a rook between the castling rook and the corner
 func (board *Board) GetFen() string {
+	return board.getFen(false)
+}
+
+// Returns the position as a Shredder-FEN, where castling rights are written as the files of the rooks
+func (board *Board) GetShredderFen() string {
+	return board.getFen(true)
+}
+
+func (board *Board) getFen(shredder bool) string {
 	var sb strings.Builder
 	sinceLastLine := 0
 	spaceCount := '0'
@@ -196,19 +252,19 @@ func (board *Board) GetFen() string {
 	sb.WriteRune(' ')
 	castleCount := 0
 	if board.whiteKingsideCastle == 1 {
-		sb.WriteRune('K')
+		sb.WriteRune(board.castlingRune(c_White, board.whiteKingsideRook, true, shredder))
 		castleCount++
 	}
 	if board.whiteQueensideCastle == 1 {
-		sb.WriteRune('Q')
+		sb.WriteRune(board.castlingRune(c_White, board.whiteQueensideRook, false, shredder))
 		castleCount++
 	}
 	if board.blackKingsideCastle == 1 {
-		sb.WriteRune('k')
+		sb.WriteRune(board.castlingRune(c_Black, board.blackKingsideRook, true, shredder))
 		castleCount++
 	}
 	if board.blackQueensideCastle == 1 {
-		sb.WriteRune('q')
+		sb.WriteRune(board.castlingRune(c_Black, board.blackQueensideRook, false, shredder))
 		castleCount++
 	}
 	if castleCount == 0 {
@@ -222,3 +278,27 @@ func (board *Board) GetFen() string {
 	sb.WriteString(strconv.Itoa(board.fullmoveNumber))
 	return sb.String()
 }
+
+func (board *Board) castlingRune(color int, rook uint8, kingside, shredder bool) rune {
+	ret := 'A' + rune(rook&7)
+	if !shredder {
+		// another rook further out would be taken for the castling rook by K or Q
+		corner := rook &^ 7
+		ret = 'Q'
+		if kingside {
+			corner = rook | 7
+			ret = 'K'
+		}
+		outer := betweenSquares[rook][corner]
+		if rook != corner {
+			outer |= uint64(1) << corner
+		}
+		if (outer & *board.PieceBBmap[color+p_Rook]) != 0 {
+			ret = 'A' + rune(rook&7)
+		}
+	}
+	if color == c_Black {
+		return unicode.ToLower(ret)
+	}
+	return ret
+}
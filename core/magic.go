@@ -0,0 +1,92 @@
+package core
+
+import "math/bits"
+
+// Magic bitboards, see https://www.chessprogramming.org/Magic_Bitboards
+// The magics are searched for at init with fixed seeds, so the tables are the same on every run
+
+type magicEntry struct {
+	mask    uint64
+	magic   uint64
+	shift   uint8
+	attacks []uint64
+}
+
+var rookMagics [64]magicEntry
+var bishopMagics [64]magicEntry
+
+type magicSliders struct{}
+
+func (magicSliders) rookAttacks(square int, occupancy uint64) uint64 {
+	entry := &rookMagics[square]
+	return entry.attacks[((occupancy&entry.mask)*entry.magic)>>entry.shift]
+}
+
+func (magicSliders) bishopAttacks(square int, occupancy uint64) uint64 {
+	entry := &bishopMagics[square]
+	return entry.attacks[((occupancy&entry.mask)*entry.magic)>>entry.shift]
+}
+
+// the relevant occupancy of a slider excludes the last square of every ray,
+// since it's attacked whether or not its occupied
+func relevantOccupancy(square int, attacks func(int, uint64) uint64) uint64 {
+	file := uint64(0x0101010101010101) << (square & 7)
+	rank := uint64(0xff) << (square & 0x38)
+	edges := ((rank1 | rank8) & ^rank) | ((0x0101010101010101 | 0x8080808080808080) & ^file)
+	return attacks(square, 0) & ^edges
+}
+
+func findMagic(square int, entry *magicEntry, attacks func(int, uint64) uint64) {
+	entry.mask = relevantOccupancy(square, attacks)
+	relevantBits := bits.OnesCount64(entry.mask)
+	entry.shift = uint8(64 - relevantBits)
+	size := 1 << relevantBits
+	occupancies := make([]uint64, 0, size)
+	reference := make([]uint64, 0, size)
+	// enumerate all subsets of the mask with the Carry-Rippler trick
+	var occ uint64
+	for {
+		occupancies = append(occupancies, occ)
+		reference = append(reference, attacks(square, occ))
+		occ = (occ - entry.mask) & entry.mask
+		if occ == 0 {
+			break
+		}
+	}
+	entry.attacks = make([]uint64, size)
+	// stores the attempt that last wrote each index, to avoid clearing the table on every attempt
+	epoch := make([]int, size)
+	rng := xorshiftRandom(magicSeeds[square>>3])
+	for attempt := 1; ; attempt++ {
+		magic := rng.sparse()
+		if bits.OnesCount64((entry.mask*magic)>>56) < 6 {
+			continue
+		}
+		ok := true
+		for i, occ := range occupancies {
+			index := (occ * magic) >> entry.shift
+			if epoch[index] < attempt {
+				epoch[index] = attempt
+				entry.attacks[index] = reference[i]
+			} else if entry.attacks[index] != reference[i] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			entry.magic = magic
+			return
+		}
+	}
+}
+
+// per rank seeds that find all magics quickly, taken from Stockfish
+var magicSeeds [8]uint64 = [8]uint64{728, 10316, 55013, 32803, 12281, 15100, 16645, 255}
+
+func generateMagics() {
+	var rotated rotatedSliders
+	for square := 0; square < 64; square++ {
+		findMagic(square, &rookMagics[square], rotated.rookAttacks)
+		findMagic(square, &bishopMagics[square], rotated.bishopAttacks)
+	}
+}
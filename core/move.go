@@ -0,0 +1,106 @@
+package core
+
+import "strings"
+
+// Moves are encoded in 16 bits as described in https://www.chessprogramming.org/Encoding_Moves
+// bits 0-5: source square, bits 6-11: target square, bits 12-15: flags
+type Move uint16
+
+const (
+	m_Quiet          = 0b0000
+	m_DoublePawnPush = 0b0001
+	m_KingCastle     = 0b0010
+	m_QueenCastle    = 0b0011
+	m_Capture        = 0b0100
+	m_EnPassant      = 0b0101
+	m_Promotion      = 0b1000
+	// promotion flags, the lower two bits select the piece (knight, bishop, rook, queen)
+	m_KnightPromotion = 0b1000
+	m_BishopPromotion = 0b1001
+	m_RookPromotion   = 0b1010
+	m_QueenPromotion  = 0b1011
+)
+
+const NullMove Move = 0
+
+func newMove(from, to uint8, flags uint16) Move {
+	return Move(uint16(from) | uint16(to)<<6 | flags<<12)
+}
+
+func (m Move) From() uint8 {
+	return uint8(m & 0x3f)
+}
+
+func (m Move) To() uint8 {
+	return uint8((m >> 6) & 0x3f)
+}
+
+func (m Move) flags() uint16 {
+	return uint16(m >> 12)
+}
+
+func (m Move) IsCapture() bool {
+	return m.flags()&m_Capture != 0
+}
+
+func (m Move) IsPromotion() bool {
+	return m.flags()&m_Promotion != 0
+}
+
+func (m Move) IsCastle() bool {
+	flags := m.flags()
+	return flags == m_KingCastle || flags == m_QueenCastle
+}
+
+// returns the piece type (p_Knight - p_Queen) the pawn promotes to
+// only valid if IsPromotion is true
+func (m Move) PromotionPiece() int {
+	return p_Knight + int(m.flags()&0b11)
+}
+
+// returns the move in long algebraic notation as used by UCI (e2e4, e7e8q)
+func (m Move) String() string {
+	if m == NullMove {
+		return "0000"
+	}
+	var sb strings.Builder
+	sb.WriteString(uint8ToAlgebraic(m.From()))
+	sb.WriteString(uint8ToAlgebraic(m.To()))
+	if m.IsPromotion() {
+		sb.WriteRune(getPieceChar(uint8(m.PromotionPiece() + c_Black)))
+	}
+	return sb.String()
+}
+
+// Returns the move in long algebraic notation for this board. In Chess960 castling is written
+// as the king taking its own rook (e1h1), since the king may not move or move like a normal king move
+func (board *Board) MoveString(m Move) string {
+	if !board.chess960 || !m.IsCastle() {
+		return m.String()
+	}
+	color := c_White
+	if m.From() >= 56 {
+		color = c_Black
+	}
+	rookFrom, _ := board.castlingRookSquares(color, m)
+	return uint8ToAlgebraic(m.From()) + uint8ToAlgebraic(rookFrom)
+}
+
+// Returns the legal move given in long algebraic notation, false if its malformed or illegal
+func (board *Board) ParseMove(algebraic string) (Move, bool) {
+	algebraic = strings.ToLower(algebraic)
+	if len(algebraic) != 4 && len(algebraic) != 5 {
+		return NullMove, false
+	}
+	for _, square := range []string{algebraic[0:2], algebraic[2:4]} {
+		if square[0] < 'a' || square[0] > 'h' || square[1] < '1' || square[1] > '8' {
+			return NullMove, false
+		}
+	}
+	for _, move := range board.GenerateLegalMoves() {
+		if board.MoveString(move) == algebraic {
+			return move, true
+		}
+	}
+	return NullMove, false
+}
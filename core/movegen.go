@@ -0,0 +1,246 @@
+package core
+
+import "math/bits"
+
+const (
+	rank1 uint64 = 0x00000000000000FF
+	rank8 uint64 = 0xFF00000000000000
+)
+
+// squares strictly between two squares on the same line, 0 if they are not aligned
+var betweenSquares [64][64]uint64
+
+// the whole rank, file or diagonal going through two squares, 0 if they are not aligned
+var lineThrough [64][64]uint64
+
+func generateLines() {
+	for a := 0; a < 64; a++ {
+		for b := 0; b < 64; b++ {
+			if a == b {
+				continue
+			}
+			bitA := uint64(1) << a
+			bitB := uint64(1) << b
+			if (rookAttacks(a, 0) & bitB) != 0 {
+				betweenSquares[a][b] = rookAttacks(a, bitB) & rookAttacks(b, bitA)
+				lineThrough[a][b] = (rookAttacks(a, 0) & rookAttacks(b, 0)) | bitA | bitB
+			} else if (bishopAttacks(a, 0) & bitB) != 0 {
+				betweenSquares[a][b] = bishopAttacks(a, bitB) & bishopAttacks(b, bitA)
+				lineThrough[a][b] = (bishopAttacks(a, 0) & bishopAttacks(b, 0)) | bitA | bitB
+			}
+		}
+	}
+}
+
+// Returns every legal move of the side to move
+func (board *Board) GenerateLegalMoves() []Move {
+	return board.generateLegalMoves(make([]Move, 0, 64))
+}
+
+// appends the legal moves to the given slice, to avoid allocating in hot loops
+func (board *Board) generateLegalMoves(moves []Move) []Move {
+	us := board.nextColor
+	them := c_Black - us
+	ours := *board.ColorBBmap[us]
+	theirs := *board.ColorBBmap[them]
+	occupancy := ours | theirs
+	king := *board.PieceBBmap[us+p_King]
+	kingSquare := bits.TrailingZeros64(king)
+
+	// the king is removed from the occupancy so it can't step back along the ray of a checking slider
+	kingTargets := kingMovesPerSquare[kingSquare] & ^ours
+	for kingTargets != 0 {
+		to := bits.TrailingZeros64(kingTargets)
+		kingTargets &= kingTargets - 1
+		if (board.AttackersTo(to, occupancy^king) & theirs) == 0 {
+			moves = appendMove(moves, uint8(kingSquare), uint8(to), theirs)
+		}
+	}
+
+	checkers := board.AttackersTo(kingSquare, occupancy) & theirs
+	checkCount := bits.OnesCount64(checkers)
+	if checkCount > 1 {
+		// only the king can escape a double check
+		return moves
+	}
+	targetMask := ^ours
+	if checkCount == 1 {
+		// capture the checker or block the check
+		targetMask = checkers | betweenSquares[kingSquare][bits.TrailingZeros64(checkers)]
+	}
+	pinned := board.PinnedPieces(us)
+
+	// a pinned knight can never move
+	knights := *board.PieceBBmap[us+p_Knight] & ^pinned
+	for knights != 0 {
+		from := bits.TrailingZeros64(knights)
+		knights &= knights - 1
+		moves = appendTargets(moves, uint8(from), knightMovesPerSquare[from]&targetMask, theirs)
+	}
+
+	queens := *board.PieceBBmap[us+p_Queen]
+	diagonal := *board.PieceBBmap[us+p_Bishop] | queens
+	for diagonal != 0 {
+		from := bits.TrailingZeros64(diagonal)
+		diagonal &= diagonal - 1
+		targets := bishopAttacks(from, occupancy) & targetMask
+		if (pinned & (uint64(1) << from)) != 0 {
+			targets &= lineThrough[kingSquare][from]
+		}
+		moves = appendTargets(moves, uint8(from), targets, theirs)
+	}
+	straight := *board.PieceBBmap[us+p_Rook] | queens
+	for straight != 0 {
+		from := bits.TrailingZeros64(straight)
+		straight &= straight - 1
+		targets := rookAttacks(from, occupancy) & targetMask
+		if (pinned & (uint64(1) << from)) != 0 {
+			targets &= lineThrough[kingSquare][from]
+		}
+		moves = appendTargets(moves, uint8(from), targets, theirs)
+	}
+
+	moves = board.generatePawnMoves(moves, kingSquare, targetMask, pinned)
+	if checkCount == 0 {
+		moves = board.generateCastlingMoves(moves)
+	}
+	return moves
+}
+
+func appendMove(moves []Move, from, to uint8, theirs uint64) []Move {
+	if (theirs & (uint64(1) << to)) != 0 {
+		return append(moves, newMove(from, to, m_Capture))
+	}
+	return append(moves, newMove(from, to, m_Quiet))
+}
+
+func appendTargets(moves []Move, from uint8, targets uint64, theirs uint64) []Move {
+	for targets != 0 {
+		to := bits.TrailingZeros64(targets)
+		targets &= targets - 1
+		moves = appendMove(moves, from, uint8(to), theirs)
+	}
+	return moves
+}
+
+// appends a pawn move, expanding it into the four promotions if it reaches the last rank
+func appendPawnMove(moves []Move, from, to uint8, flags uint16) []Move {
+	if ((rank1 | rank8) & (uint64(1) << to)) != 0 {
+		for promotion := uint16(m_KnightPromotion); promotion <= m_QueenPromotion; promotion++ {
+			moves = append(moves, newMove(from, to, flags|promotion))
+		}
+		return moves
+	}
+	return append(moves, newMove(from, to, flags))
+}
+
+// appends the pawn moves to the targets, each from the pawn offset squares behind its target
+func appendPawnTargets(moves []Move, targets uint64, offset int, flags uint16) []Move {
+	for targets != 0 {
+		to := bits.TrailingZeros64(targets)
+		targets &= targets - 1
+		moves = appendPawnMove(moves, uint8(to-offset), uint8(to), flags)
+	}
+	return moves
+}
+
+// https://www.chessprogramming.org/Pawn_Pushes_(Bitboards) and https://www.chessprogramming.org/Pawn_Attacks_(Bitboards)
+// The moves of a set of pawns are generated at once, pinned pawns are passed one by one
+// since each of them has its own line to stay on
+func (board *Board) generatePawnMoves(moves []Move, kingSquare int, targetMask, pinned uint64) []Move {
+	us := board.nextColor
+	pawns := *board.PieceBBmap[us+p_Pawn]
+	moves = board.appendSetwisePawnMoves(moves, pawns&^pinned, targetMask)
+	for pinnedPawns := pawns & pinned; pinnedPawns != 0; pinnedPawns &= pinnedPawns - 1 {
+		from := bits.TrailingZeros64(pinnedPawns)
+		moves = board.appendSetwisePawnMoves(moves, uint64(1)<<from, targetMask&lineThrough[kingSquare][from])
+	}
+
+	capturers := board.PawnsAbleToCaptureEnPassant(us)
+	if capturers == 0 {
+		return moves
+	}
+	// en passant removes two pieces from a line at once, so instead of relying on
+	// the pin and check masks the resulting position is tested directly
+	theirs := *board.ColorBBmap[c_Black-us]
+	occupancy := ^board.emptySquares
+	epBit := uint64(1) << board.enPassantSquare
+	capturedBit := nortOne(epBit)
+	if us == c_White {
+		capturedBit = soutOne(epBit)
+	}
+	for ; capturers != 0; capturers &= capturers - 1 {
+		from := bits.TrailingZeros64(capturers)
+		after := (occupancy ^ uint64(1)<<from ^ capturedBit) | epBit
+		if (board.AttackersTo(kingSquare, after) & theirs & ^capturedBit) == 0 {
+			moves = append(moves, newMove(uint8(from), board.enPassantSquare, m_EnPassant))
+		}
+	}
+	return moves
+}
+
+// appends the pushes and captures of the given pawns of the side to move that land on the mask,
+// en passant not included
+func (board *Board) appendSetwisePawnMoves(moves []Move, pawns, mask uint64) []Move {
+	us := board.nextColor
+	theirs := *board.ColorBBmap[c_Black-us]
+	// the distances from the target squares back to the pawns
+	pushOffset, eastOffset, westOffset := 8, 9, 7
+	doublePushRank := rank4
+	forward := nortOne
+	if us == c_Black {
+		pushOffset, eastOffset, westOffset = -8, -7, -9
+		doublePushRank = rank5
+		forward = soutOne
+	}
+	singlePushes := forward(pawns) & board.emptySquares
+	doublePushes := forward(singlePushes) & board.emptySquares & doublePushRank
+	moves = appendPawnTargets(moves, singlePushes&mask, pushOffset, m_Quiet)
+	moves = appendPawnTargets(moves, doublePushes&mask, 2*pushOffset, m_DoublePawnPush)
+	moves = appendPawnTargets(moves, pawnEastAttacks(us, pawns)&theirs&mask, eastOffset, m_Capture)
+	return appendPawnTargets(moves, pawnWestAttacks(us, pawns)&theirs&mask, westOffset, m_Capture)
+}
+
+// https://www.chessprogramming.org/Castling and https://www.chessprogramming.org/Chess960
+// The king ends on the g or c file and the rook next to it on the f or d file, wherever they started
+func (board *Board) generateCastlingMoves(moves []Move) []Move {
+	if board.nextColor == c_White {
+		moves = board.appendCastle(moves, board.whiteKingsideCastle, board.whiteKingsideRook, 6, m_KingCastle)
+		return board.appendCastle(moves, board.whiteQueensideCastle, board.whiteQueensideRook, 2, m_QueenCastle)
+	}
+	moves = board.appendCastle(moves, board.blackKingsideCastle, board.blackKingsideRook, 62, m_KingCastle)
+	return board.appendCastle(moves, board.blackQueensideCastle, board.blackQueensideRook, 58, m_QueenCastle)
+}
+
+// appends the castling move if every square the king and the rook go through is empty
+// and the king doesn't start, pass or end on an attacked square
+func (board *Board) appendCastle(moves []Move, right int, rookSquare uint8, kingTarget int, flags uint16) []Move {
+	us := board.nextColor
+	rookBit := uint64(1) << rookSquare
+	king := *board.PieceBBmap[us+p_King]
+	if right == 0 || king == 0 || (*board.PieceBBmap[us+p_Rook]&rookBit) == 0 {
+		return moves
+	}
+	kingSquare := bits.TrailingZeros64(king)
+	rookTarget := kingTarget - 1
+	if flags == m_QueenCastle {
+		rookTarget = kingTarget + 1
+	}
+	// the king and the rook may jump over each other, so they don't block themselves
+	occupancy := ^board.emptySquares ^ king ^ rookBit
+	kingPath := betweenSquares[kingSquare][kingTarget] | uint64(1)<<kingTarget
+	rookPath := betweenSquares[rookSquare][rookTarget] | uint64(1)<<rookTarget
+	if (occupancy & (kingPath | rookPath)) != 0 {
+		return moves
+	}
+	theirs := *board.ColorBBmap[c_Black-us]
+	kingPath |= king
+	for kingPath != 0 {
+		square := bits.TrailingZeros64(kingPath)
+		kingPath &= kingPath - 1
+		if (board.AttackersTo(square, occupancy) & theirs) != 0 {
+			return moves
+		}
+	}
+	return append(moves, newMove(uint8(kingSquare), uint8(kingTarget), flags))
+}
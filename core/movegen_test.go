@@ -0,0 +1,165 @@
+package core
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLegalMoveCountSimplePositions(t *testing.T) {
+	file, err := os.Open("data/simple_positions.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		// Format: <fen> <expected_amount_of_moves> ;<comments>
+		line := strings.Split(scanner.Text(), ";")[0]
+		fields := strings.Fields(line)
+		if len(fields) != 7 {
+			continue
+		}
+		fen := strings.Join(fields[:6], " ")
+		expected, err := strconv.Atoi(fields[6])
+		if err != nil {
+			t.Errorf("Bad expected move count in line: %s", scanner.Text())
+			continue
+		}
+		var board Board
+		board.LoadFen(fen)
+		moves := board.GenerateLegalMoves()
+		if len(moves) != expected {
+			t.Errorf("\nLegal move count failed for %s\nExpected:%d\nGot     :%d\nMoves   :%v", fen, expected, len(moves), moves)
+		}
+	}
+}
+
+func TestLegalMovesSpecial(t *testing.T) {
+	type testCase struct {
+		fen      string
+		move     string
+		expected bool
+	}
+	testCases := []testCase{
+		// Test cases with fen, a move and whether its expected to be legal
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1g1", true},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", "e1c1", true},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", "e8c8", true},
+		// Castling through an attacked square
+		{"r3k2r/8/8/8/8/8/5r2/R3K2R w KQkq - 0 1", "e1g1", false},
+		// b1 may be attacked when castling queenside
+		{"r3k2r/8/8/8/8/8/1r6/R3K2R w KQkq - 0 1", "e1c1", true},
+		// Castling out of check
+		{"r3k2r/8/8/8/8/8/4r3/R3K2R w KQkq - 0 1", "e1g1", false},
+		// Castling without rights
+		{"r3k2r/8/8/8/8/8/8/R3K2R w Qkq - 0 1", "e1g1", false},
+		{"8/8/8/3pP3/8/8/8/4K2k w - d6 0 1", "e5d6", true},
+		// En passant that would expose the king on the rank
+		{"8/8/8/K2pP2r/8/8/8/7k w - d6 0 1", "e5d6", false},
+		// En passant capturing the checking pawn
+		{"8/8/8/2kpP3/8/8/8/4K3 w - d6 0 1", "e5d6", true},
+		{"8/1P6/8/8/8/8/8/k3K3 w - - 0 1", "b7b8q", true},
+		{"8/1P6/8/8/8/8/8/k3K3 w - - 0 1", "b7b8n", true},
+		{"2r5/1P6/8/8/8/8/8/k3K3 w - - 0 1", "b7c8r", true},
+		// The king can't move along the line of a checking slider
+		{"4r3/8/8/8/8/8/4K3/7k w - - 0 1", "e2e1", false},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		found := false
+		for _, move := range board.GenerateLegalMoves() {
+			if move.String() == test.move {
+				found = true
+			}
+		}
+		if found != test.expected {
+			t.Errorf("\nLegal move test failed for %s\nMove:%s\nExpected legal:%t", test.fen, test.move, test.expected)
+		}
+	}
+}
+
+func TestCastling(t *testing.T) {
+	type testCase struct {
+		fen      string
+		chess960 bool
+		move     string
+		expected string // the position after the move, empty if its illegal
+	}
+	testCases := []testCase{
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, "e1g1", "r3k2r/8/8/8/8/8/8/R4RK1 b kq - 1 1"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", false, "e8c8", "2kr3r/8/8/8/8/8/8/R3K2R w KQ - 1 2"},
+		// the rights are lost when a rook is captured on its starting square
+		{"r3k2r/8/8/8/8/8/8/R3K2R b KQkq - 0 1", false, "a8a1", "4k2r/8/8/8/8/8/8/r3K2R w Kk - 0 2"},
+		{"r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1", false, "e1f1", "r3k2r/8/8/8/8/8/8/R4K1R b kq - 1 1"},
+		// Chess960, castling is written as the king taking the rook
+		{"4k3/8/8/8/8/8/8/6KR w H - 0 1", true, "g1h1", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		{"4k3/8/8/8/8/8/8/RK6 w A - 0 1", true, "b1a1", "4k3/8/8/8/8/8/8/2KR4 b - - 1 1"},
+		// the king and the rook swap squares
+		{"4k3/8/8/8/8/8/8/5KR1 w G - 0 1", true, "f1g1", "4k3/8/8/8/8/8/8/5RK1 b - - 1 1"},
+		{"4k3/8/8/8/8/8/8/2RK4 w C - 0 1", true, "d1c1", "4k3/8/8/8/8/8/8/2KR4 b - - 1 1"},
+		// the rook lands on d1, which is occupied
+		{"4k3/8/8/8/8/8/8/RK1N4 w A - 0 1", true, "b1a1", ""},
+		// the king ends on c1, which the rook on a1 attacks once the castling rook is gone
+		{"4k3/8/8/8/8/8/8/r1RK4 w C - 0 1", true, "d1c1", ""},
+		// the king goes through f1
+		{"4k3/8/8/8/8/8/5r2/1R2K1R1 w G - 0 1", true, "e1g1", ""},
+		// the outer rook doesn't get in the way of castling with the inner one
+		{"4k3/8/8/8/8/8/8/1R2K1RR w G - 0 1", true, "e1g1", "4k3/8/8/8/8/8/8/1R3RKR b - - 1 1"},
+		{"rk6/8/8/8/8/8/8/4K3 b a - 0 1", true, "b8a8", "2kr4/8/8/8/8/8/8/4K3 w - - 1 2"},
+		// moving the rook loses the right
+		{"4k3/8/8/8/8/8/8/RK6 w A - 0 1", true, "a1a2", "4k3/8/8/8/8/8/R7/1K6 b - - 1 1"},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		board.SetChess960(test.chess960)
+		move, ok := board.ParseMove(test.move)
+		if !ok {
+			if test.expected != "" {
+				t.Errorf("\nCastling test failed for %s\nMove %s is illegal", test.fen, test.move)
+			}
+			continue
+		}
+		if test.expected == "" {
+			t.Errorf("\nCastling test failed for %s\nMove %s is legal", test.fen, test.move)
+			continue
+		}
+		fen := board.GetFen()
+		board.MakeMove(move)
+		if board.GetFen() != test.expected {
+			t.Errorf("\nCastling test failed for %s after %s\nExpected:%s\n     Got:%s", test.fen, test.move, test.expected, board.GetFen())
+		}
+		hash := board.zobristHash
+		if board.recalculateZobrist(); board.zobristHash != hash {
+			t.Errorf("Zobrist hash not updated by %s in %s", test.move, test.fen)
+		}
+		board.UnmakeMove()
+		if board.GetFen() != fen {
+			t.Errorf("\nCastling not unmade for %s after %s\nExpected:%s\n     Got:%s", test.fen, test.move, fen, board.GetFen())
+		}
+	}
+
+	// standard castling is written with the king's target square
+	var board Board
+	board.LoadFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	if _, ok := board.ParseMove("e1h1"); ok {
+		t.Error("Castling written as the king taking the rook outside of Chess960")
+	}
+	board.SetChess960(true)
+	if _, ok := board.ParseMove("e1g1"); ok {
+		t.Error("Castling written with the king's target square in Chess960")
+	}
+}
+
+func BenchmarkGenerateLegalMoves(b *testing.B) {
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	moves := make([]Move, 0, 256)
+	for i := 0; i < b.N; i++ {
+		moves = board.generateLegalMoves(moves[:0])
+	}
+}
@@ -29,3 +29,62 @@ func (board *Board) PawnsAbleToPush(color int) uint64 {
 		return nortOne(board.emptySquares) & *pawnMap
 	}
 }
+
+// https://www.chessprogramming.org/Pawn_Attacks_(Bitboards)
+// East is towards the h file and west towards the a file, the file masks keep the attacks from wrapping around the board
+func pawnEastAttacks(color int, pawns uint64) uint64 {
+	if color == c_White {
+		return noEaOne(pawns)
+	} else {
+		return soEaOne(pawns)
+	}
+}
+
+func pawnWestAttacks(color int, pawns uint64) uint64 {
+	if color == c_White {
+		return noWeOne(pawns)
+	} else {
+		return soWeOne(pawns)
+	}
+}
+
+// returns the squares attacked by the given pawns of a color
+func pawnAttacks(color int, pawns uint64) uint64 {
+	return pawnEastAttacks(color, pawns) | pawnWestAttacks(color, pawns)
+}
+
+func (board *Board) PawnEastAttacks(color int) uint64 {
+	return pawnEastAttacks(color, *board.PieceBBmap[color+p_Pawn])
+}
+
+func (board *Board) PawnWestAttacks(color int) uint64 {
+	return pawnWestAttacks(color, *board.PieceBBmap[color+p_Pawn])
+}
+
+func (board *Board) PawnAnyAttacks(color int) uint64 {
+	return pawnAttacks(color, *board.PieceBBmap[color+p_Pawn])
+}
+
+// squares attacked twice by the pawns of a color
+func (board *Board) PawnDoubleAttacks(color int) uint64 {
+	return board.PawnEastAttacks(color) & board.PawnWestAttacks(color)
+}
+
+// enemy pieces that can be captured by the pawns of a color, en passant not included
+func (board *Board) PawnCaptureTargets(color int) uint64 {
+	return board.PawnAnyAttacks(color) & *board.ColorBBmap[c_Black-color]
+}
+
+// returns the pawns of a color that can capture en passant, the square behind a pawn that just
+// made a double push is only a target for the other color
+func (board *Board) PawnsAbleToCaptureEnPassant(color int) uint64 {
+	if board.enPassantSquare == 0xFF {
+		return 0
+	}
+	epBit := uint64(1) << board.enPassantSquare
+	if color == c_White && (epBit&rank6) == 0 || color == c_Black && (epBit&rank3) == 0 {
+		return 0
+	}
+	// the pawns that would attack the square if it were theirs
+	return pawnAttacks(c_Black-color, epBit) & *board.PieceBBmap[color+p_Pawn]
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func moveStrings(moves []Move) []string {
+	ret := make([]string, len(moves))
+	for i, move := range moves {
+		ret[i] = move.String()
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func TestPawnAttacks(t *testing.T) {
+	type testCase struct {
+		fen   string
+		color int
+		east  uint64
+		west  uint64
+	}
+	testCases := []testCase{
+		// pawns on the edge files only attack towards the center
+		{"4k3/8/8/8/8/8/P6P/4K3 w - - 0 1", c_White, squaresToBitboard("b3"), squaresToBitboard("g3")},
+		{"4k3/p6p/8/8/8/8/8/4K3 b - - 0 1", c_Black, squaresToBitboard("b6"), squaresToBitboard("g6")},
+		{"4k3/8/8/8/8/8/3PP3/4K3 w - - 0 1", c_White, squaresToBitboard("e3", "f3"), squaresToBitboard("c3", "d3")},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", c_White, 0, 0},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		if east := board.PawnEastAttacks(test.color); east != test.east {
+			t.Errorf("%s east attacks\nExpected:%064b\n     Got:%064b", test.fen, test.east, east)
+		}
+		if west := board.PawnWestAttacks(test.color); west != test.west {
+			t.Errorf("%s west attacks\nExpected:%064b\n     Got:%064b", test.fen, test.west, west)
+		}
+		if attacks := board.PawnAnyAttacks(test.color); attacks != test.east|test.west {
+			t.Errorf("%s attacks\nExpected:%064b\n     Got:%064b", test.fen, test.east|test.west, attacks)
+		}
+	}
+
+	var board Board
+	board.LoadFen("4k3/8/8/8/8/8/3PP3/4K3 w - - 0 1")
+	if double := board.PawnDoubleAttacks(c_White); double != 0 {
+		t.Errorf("Unexpected double attacks %064b", double)
+	}
+	board.LoadFen("4k3/8/8/8/8/8/2P1P3/4K3 w - - 0 1")
+	if double := board.PawnDoubleAttacks(c_White); double != squaresToBitboard("d3") {
+		t.Errorf("Bad double attacks %064b", double)
+	}
+}
+
+func TestPawnCaptureTargets(t *testing.T) {
+	type testCase struct {
+		fen           string
+		color         int
+		targets       uint64
+		ableEnPassant uint64
+	}
+	testCases := []testCase{
+		// the knights on a4 and h3 would be captured if the attacks wrapped around the board
+		{"4k3/8/8/8/n7/1n4nn/P6P/4K3 w - - 0 1", c_White,
+			squaresToBitboard("b3", "g3"), 0},
+		{"4k3/p6p/1N4NN/N7/8/8/8/4K3 b - - 0 1", c_Black,
+			squaresToBitboard("b6", "g6"), 0},
+		// own pieces aren't targets
+		{"4k3/8/8/8/8/1N6/P7/4K3 w - - 0 1", c_White, 0, 0},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 w - - 0 1", c_White, squaresToBitboard("d5"), 0},
+		{"4k3/8/8/3p4/4P3/8/8/4K3 b - - 0 1", c_Black, squaresToBitboard("e4"), 0},
+		// en passant on both sides of the pawn that was pushed
+		{"4k3/8/8/2PpP3/8/8/8/4K3 w - d6 0 1", c_White, 0, squaresToBitboard("c5", "e5")},
+		// the h5 pawn doesn't reach a6 on the other side of the board
+		{"4k3/8/8/p6P/8/8/8/4K3 w - a6 0 1", c_White, 0, 0},
+		{"4k3/8/8/pP6/8/8/8/4K3 w - a6 0 1", c_White, 0, squaresToBitboard("b5")},
+		{"4k3/8/8/8/Pp5p/8/8/4K3 b - a3 0 1", c_Black, 0, squaresToBitboard("b4")},
+		// the en passant square belongs to the side to move
+		{"4k3/8/8/8/Pp6/8/8/4K3 b - a3 0 1", c_White, 0, 0},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		if targets := board.PawnCaptureTargets(test.color); targets != test.targets {
+			t.Errorf("%s capture targets\nExpected:%064b\n     Got:%064b", test.fen, test.targets, targets)
+		}
+		if pawns := board.PawnsAbleToCaptureEnPassant(test.color); pawns != test.ableEnPassant {
+			t.Errorf("%s pawns capturing en passant\nExpected:%064b\n     Got:%064b", test.fen, test.ableEnPassant, pawns)
+		}
+	}
+}
+
+// the pawn captures, including en passant and the promotions, among the legal moves
+func TestPawnCaptureMoves(t *testing.T) {
+	type testCase struct {
+		fen      string
+		expected []string
+	}
+	testCases := []testCase{
+		{"1n2k3/P7/8/8/8/8/8/4K3 w - - 0 1", []string{"a7b8b", "a7b8n", "a7b8q", "a7b8r"}},
+		// the rook on a2 is where h2 would capture if the attacks wrapped around
+		{"4k3/8/8/8/8/8/R6p/4K1N1 b - - 0 1", []string{"h2g1b", "h2g1n", "h2g1q", "h2g1r"}},
+		{"4k3/8/8/2PpP3/8/8/8/4K3 w - d6 0 1", []string{"c5d6", "e5d6"}},
+		// the pinned pawn can only capture the bishop pinning it
+		{"4k3/8/8/8/1b1p4/2P5/8/4K3 w - - 0 1", []string{"c3b4"}},
+		{"4k3/8/8/8/8/8/8/4K3 w - - 0 1", []string{}},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		var captures []Move
+		for _, move := range board.GenerateLegalMoves() {
+			if board.pieceAt(move.From())%6 == p_Pawn && (move.IsCapture() || move.flags() == m_EnPassant) {
+				captures = append(captures, move)
+			}
+		}
+		if got := moveStrings(captures); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("%s\nExpected:%v\n     Got:%v", test.fen, test.expected, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package core
+
+import "fmt"
+
+// when enabled, perft verifies the incrementally updated zobrist hash after every move
+var debugZobrist bool = false
+
+func (board *Board) verifyZobrist(move Move) {
+	incremental := board.zobristHash
+	board.recalculateZobrist()
+	if incremental != board.zobristHash {
+		panic(fmt.Sprintf("Incremental zobrist hash mismatch after %s in %s\nExpected:%016x\n     Got:%016x",
+			move, board.GetFen(), board.zobristHash, incremental))
+	}
+}
+
+// https://www.chessprogramming.org/Perft
+// Counts the leaf nodes of the legal move tree to the given depth
+func (board *Board) Perft(depth int) uint64 {
+	if depth <= 0 {
+		return 1
+	}
+	moves := board.generateLegalMoves(make([]Move, 0, 64))
+	// bulk counting, the leaves don't need to be played
+	if depth == 1 {
+		return uint64(len(moves))
+	}
+	var nodes uint64
+	for _, move := range moves {
+		board.MakeMove(move)
+		if debugZobrist {
+			board.verifyZobrist(move)
+		}
+		nodes += board.Perft(depth - 1)
+		board.UnmakeMove()
+	}
+	return nodes
+}
+
+// Returns the perft node count of every legal move, keyed by the move in long algebraic notation
+// to be compared with the divide output of other engines
+func (board *Board) PerftDivide(depth int) map[string]uint64 {
+	ret := make(map[string]uint64)
+	if depth <= 0 {
+		return ret
+	}
+	for _, move := range board.GenerateLegalMoves() {
+		board.MakeMove(move)
+		ret[board.MoveString(move)] = board.Perft(depth - 1)
+		board.UnmakeMove()
+	}
+	return ret
+}
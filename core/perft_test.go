@@ -0,0 +1,130 @@
+package core
+
+import (
+	"sort"
+	"testing"
+)
+
+type perftTestCase struct {
+	name     string
+	fen      string
+	expected []uint64 // node counts starting at depth 1
+}
+
+// Positions and node counts from https://www.chessprogramming.org/Perft_Results
+var perftTestCases = []perftTestCase{
+	{"Start position", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", []uint64{20, 400, 8902, 197281, 4865609}},
+	{"Kiwipete", "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1", []uint64{48, 2039, 97862, 4085603}},
+	{"Position 3", "8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1", []uint64{14, 191, 2812, 43238, 674624}},
+	{"Position 4", "r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"Position 4 mirrored", "r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1", []uint64{6, 264, 9467, 422333}},
+	{"Position 5", "rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8", []uint64{44, 1486, 62379, 2103487}},
+	{"Position 6", "r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10", []uint64{46, 2079, 89890, 3894594}},
+}
+
+func TestPerft(t *testing.T) {
+	for _, test := range perftTestCases {
+		var board Board
+		if loaded, err := board.LoadFen(test.fen); !loaded {
+			t.Fatal(err)
+		}
+		for i, expected := range test.expected {
+			depth := i + 1
+			// the deepest searches take a few seconds
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			nodes := board.Perft(depth)
+			if nodes != expected {
+				t.Errorf("\nPerft failed for %s at depth %d\nExpected:%d\nGot     :%d", test.name, depth, expected, nodes)
+			}
+			if fen := board.GetFen(); fen != test.fen {
+				t.Errorf("\nPerft didn't restore the board for %s\nExpected:%s\nGot     :%s", test.name, test.fen, fen)
+			}
+		}
+	}
+}
+
+// Positions and node counts from https://www.chessprogramming.org/Chess960_Perft_Results
+var chess960PerftTestCases = []perftTestCase{
+	{"Chess960 1", "bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9", []uint64{21, 528, 12189, 326672}},
+	{"Chess960 2", "2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9", []uint64{21, 807, 18002, 667366}},
+	{"Chess960 3", "b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9", []uint64{20, 479, 10471, 273318}},
+	{"Chess960 5", "1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9", []uint64{28, 1120, 31058, 1171749}},
+}
+
+func TestPerftChess960(t *testing.T) {
+	debugZobrist = true
+	defer func() {
+		debugZobrist = false
+		if err := recover(); err != nil {
+			t.Error(err)
+		}
+	}()
+	for _, test := range chess960PerftTestCases {
+		var board Board
+		if loaded, err := board.LoadFen(test.fen); !loaded {
+			t.Fatal(err)
+		}
+		for i, expected := range test.expected {
+			depth := i + 1
+			if testing.Short() && expected > 100000 {
+				break
+			}
+			nodes := board.Perft(depth)
+			if nodes != expected {
+				t.Errorf("\nPerft failed for %s at depth %d\nExpected:%d\nGot     :%d", test.name, depth, expected, nodes)
+			}
+			if fen := board.GetShredderFen(); fen != test.fen {
+				t.Errorf("\nPerft didn't restore the board for %s\nExpected:%s\nGot     :%s", test.name, test.fen, fen)
+			}
+		}
+	}
+}
+
+func TestPerftDivide(t *testing.T) {
+	var board Board
+	board.LoadFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	divide := board.PerftDivide(3)
+	if len(divide) != 20 {
+		t.Errorf("Expected 20 moves, got %d", len(divide))
+	}
+	var total uint64
+	moves := make([]string, 0, len(divide))
+	for move, nodes := range divide {
+		total += nodes
+		moves = append(moves, move)
+	}
+	sort.Strings(moves)
+	if total != 8902 {
+		t.Errorf("Divide total doesn't match perft\nExpected:%d\nGot     :%d\nMoves   :%v", 8902, total, moves)
+	}
+	// known divide values
+	if divide["e2e4"] != 600 || divide["g1f3"] != 440 || divide["a2a3"] != 380 {
+		t.Errorf("Bad divide values e2e4:%d g1f3:%d a2a3:%d", divide["e2e4"], divide["g1f3"], divide["a2a3"])
+	}
+}
+
+func BenchmarkPerftKiwipete(b *testing.B) {
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		board.Perft(3)
+	}
+}
+
+func TestPerftZobristConsistency(t *testing.T) {
+	debugZobrist = true
+	defer func() {
+		debugZobrist = false
+		if err := recover(); err != nil {
+			t.Error(err)
+		}
+	}()
+	for _, test := range perftTestCases {
+		var board Board
+		board.LoadFen(test.fen)
+		// perft doesn't play the moves at depth 1, so this checks every move up to depth 3
+		board.Perft(4)
+	}
+}
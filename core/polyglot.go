@@ -0,0 +1,168 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/bits"
+	"math/rand"
+	"os"
+	"sort"
+)
+
+// Polyglot opening books, see http://hgm.nubati.net/book_format.html
+
+// Returns the hash of the position as used by Polyglot books
+func (board *Board) PolyglotHash() uint64 {
+	var ret uint64
+	for piece := 0; piece < 12; piece++ {
+		// polyglot orders the pieces as black pawn, white pawn, black knight...
+		kind := 2 * (piece % 6)
+		if piece < c_Black {
+			kind++
+		}
+		bb := *board.PieceBBmap[piece]
+		for bb != 0 {
+			square := bits.TrailingZeros64(bb)
+			bb &= bb - 1
+			ret ^= polyglotRandom[64*kind+square]
+		}
+	}
+	castling := [4]int{board.whiteKingsideCastle, board.whiteQueensideCastle, board.blackKingsideCastle, board.blackQueensideCastle}
+	for i, right := range castling {
+		if right == 1 {
+			ret ^= polyglotRandom[768+i]
+		}
+	}
+	// the en passant file only counts if a pawn is able to capture
+	if board.enPassantCapturable() {
+		ret ^= polyglotRandom[772+int(board.enPassantCol)]
+	}
+	if board.nextColor == c_White {
+		ret ^= polyglotRandom[780]
+	}
+	return ret
+}
+
+type polyglotEntry struct {
+	key    uint64
+	move   uint16
+	weight uint16
+	learn  uint32
+}
+
+type BookMove struct {
+	Move   Move
+	Weight int
+}
+
+type OpeningBook struct {
+	// sorted by key, as in the file
+	entries []polyglotEntry
+}
+
+func LoadPolyglotBook(path string) (*OpeningBook, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ReadPolyglotBook(file)
+}
+
+func ReadPolyglotBook(reader io.Reader) (*OpeningBook, error) {
+	book := &OpeningBook{}
+	var buffer [16]byte
+	for {
+		_, err := io.ReadFull(reader, buffer[:])
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("Bad polyglot book, size is not a multiple of 16 bytes")
+		}
+		book.entries = append(book.entries, polyglotEntry{
+			key:    binary.BigEndian.Uint64(buffer[0:8]),
+			move:   binary.BigEndian.Uint16(buffer[8:10]),
+			weight: binary.BigEndian.Uint16(buffer[10:12]),
+			learn:  binary.BigEndian.Uint32(buffer[12:16]),
+		})
+	}
+	if !sort.SliceIsSorted(book.entries, func(i, j int) bool { return book.entries[i].key < book.entries[j].key }) {
+		return nil, errors.New("Bad polyglot book, entries are not sorted")
+	}
+	return book, nil
+}
+
+// polyglot moves are encoded as to file, to row, from file, from row, promotion piece (3 bits each)
+// castling is encoded as the king capturing its own rook
+func (board *Board) polyglotToMove(encoded uint16, legalMoves []Move) Move {
+	to := uint8(encoded & 0x3f)
+	from := uint8((encoded >> 6) & 0x3f)
+	promotion := int((encoded >> 12) & 0x7)
+	king := *board.PieceBBmap[board.nextColor+p_King]
+	rooks := *board.PieceBBmap[board.nextColor+p_Rook]
+	if (king&(uint64(1)<<from)) != 0 && (rooks&(uint64(1)<<to)) != 0 {
+		kingside := to > from
+		for _, move := range legalMoves {
+			if move.IsCastle() && (move.flags() == m_KingCastle) == kingside {
+				return move
+			}
+		}
+		return NullMove
+	}
+	for _, move := range legalMoves {
+		if move.From() != from || move.To() != to {
+			continue
+		}
+		if move.IsPromotion() != (promotion != 0) {
+			continue
+		}
+		if promotion != 0 && move.PromotionPiece() != promotion {
+			continue
+		}
+		return move
+	}
+	return NullMove
+}
+
+// Returns the legal book moves of the position and their weights
+func (book *OpeningBook) Moves(board *Board) []BookMove {
+	key := board.PolyglotHash()
+	first := sort.Search(len(book.entries), func(i int) bool { return book.entries[i].key >= key })
+	var ret []BookMove
+	legalMoves := board.GenerateLegalMoves()
+	for i := first; i < len(book.entries) && book.entries[i].key == key; i++ {
+		move := board.polyglotToMove(book.entries[i].move, legalMoves)
+		if move != NullMove {
+			ret = append(ret, BookMove{Move: move, Weight: int(book.entries[i].weight)})
+		}
+	}
+	return ret
+}
+
+// Picks a book move with a probability proportional to its weight, using the global source if rng is nil
+// returns false if the position is not in the book
+func (book *OpeningBook) PickMove(board *Board, rng *rand.Rand) (Move, bool) {
+	moves := book.Moves(board)
+	total := 0
+	for _, move := range moves {
+		total += move.Weight
+	}
+	if total == 0 {
+		return NullMove, false
+	}
+	var pick int
+	if rng != nil {
+		pick = rng.Intn(total)
+	} else {
+		pick = rand.Intn(total)
+	}
+	for _, move := range moves {
+		pick -= move.Weight
+		if pick < 0 {
+			return move.Move, true
+		}
+	}
+	return NullMove, false
+}
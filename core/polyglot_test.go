@@ -0,0 +1,123 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// plays moves given in long algebraic notation
+func playMoves(t *testing.T, board *Board, moves []string) {
+	for _, algebraic := range moves {
+		found := false
+		for _, move := range board.GenerateLegalMoves() {
+			if move.String() == algebraic {
+				board.MakeMove(move)
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Illegal move %s in %s", algebraic, board.GetFen())
+		}
+	}
+}
+
+func TestPolyglotHash(t *testing.T) {
+	type testCase struct {
+		moves    []string
+		expected uint64
+	}
+	// Reference keys from the Polyglot book format specification
+	testCases := []testCase{
+		{[]string{}, 0x463b96181691fc9c},
+		{[]string{"e2e4"}, 0x823c9b50fd114196},
+		{[]string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+		{[]string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+		{[]string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+		{[]string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4", "b4c3", "a1a3"}, 0x5c3f9b829b279560},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+		playMoves(t, &board, test.moves)
+		if hash := board.PolyglotHash(); hash != test.expected {
+			t.Errorf("\nBad polyglot hash after %v\nExpected:%016x\n     Got:%016x", test.moves, test.expected, hash)
+		}
+	}
+}
+
+func writeBookEntry(buffer *bytes.Buffer, key uint64, from, to string, promotion, weight uint16) {
+	fromSquare, toSquare := uint16(AlgebraicToUint8(from)), uint16(AlgebraicToUint8(to))
+	binary.Write(buffer, binary.BigEndian, key)
+	binary.Write(buffer, binary.BigEndian, toSquare|fromSquare<<6|promotion<<12)
+	binary.Write(buffer, binary.BigEndian, weight)
+	binary.Write(buffer, binary.BigEndian, uint32(0))
+}
+
+func TestPolyglotBook(t *testing.T) {
+	var castling Board
+	castling.LoadFen("r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1")
+	var promotion Board
+	promotion.LoadFen("8/1P6/8/8/8/8/8/k3K3 w - - 0 1")
+	var buffer bytes.Buffer
+	entries := []struct {
+		key       uint64
+		from, to  string
+		promotion uint16
+		weight    uint16
+	}{
+		{castling.PolyglotHash(), "e1", "h1", 0, 1},
+		{castling.PolyglotHash(), "e1", "a1", 0, 1},
+		{0x463b96181691fc9c, "e2", "e4", 0, 30},
+		{0x463b96181691fc9c, "d2", "d4", 0, 10},
+		{promotion.PolyglotHash(), "b7", "b8", 1, 5},
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	for _, entry := range entries {
+		writeBookEntry(&buffer, entry.key, entry.from, entry.to, entry.promotion, entry.weight)
+	}
+	book, err := ReadPolyglotBook(&buffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var board Board
+	board.LoadFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	moves := book.Moves(&board)
+	if len(moves) != 2 || moves[0].Move.String() != "e2e4" || moves[0].Weight != 30 || moves[1].Move.String() != "d2d4" {
+		t.Errorf("Bad book moves for the start position: %v", moves)
+	}
+	rng := rand.New(rand.NewSource(1))
+	picked := map[string]int{}
+	for i := 0; i < 400; i++ {
+		move, ok := book.PickMove(&board, rng)
+		if !ok {
+			t.Fatal("No book move picked")
+		}
+		picked[move.String()]++
+	}
+	if picked["e2e4"] <= picked["d2d4"] {
+		t.Errorf("Weights not respected: %v", picked)
+	}
+
+	moves = book.Moves(&castling)
+	if len(moves) != 2 || !moves[0].Move.IsCastle() || !moves[1].Move.IsCastle() {
+		t.Errorf("Bad castling book moves: %v", moves)
+	}
+	moves = book.Moves(&promotion)
+	if len(moves) != 1 || moves[0].Move.String() != "b7b8n" {
+		t.Errorf("Bad promotion book moves: %v", moves)
+	}
+
+	var empty Board
+	empty.LoadFen("8/8/8/8/8/8/8/k3K3 w - - 0 1")
+	if _, ok := book.PickMove(&empty, rng); ok {
+		t.Error("Picked a book move for a position not in the book")
+	}
+}
@@ -1,43 +1,575 @@
 package core
 
-import "math"
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
+// Negamax search with iterative deepening, see https://www.chessprogramming.org/Negamax
+// Scores are in centipawns from the point of view of the side to move
+
+const (
+	maxPly        = 128
+	scoreInfinity = 1000000
+	// mates are scored as scoreMate minus the distance to the mate in plies, so shorter mates are preferred
+	scoreMate = 900000
+)
+
+func isMateScore(score int) bool {
+	return score > scoreMate-maxPly || score < -scoreMate+maxPly
+}
+
+// the table stores mate scores relative to the position, the search relative to the root
+func scoreToTT(score, ply int) int {
+	if score > scoreMate-maxPly {
+		return score + ply
+	} else if score < -scoreMate+maxPly {
+		return score - ply
+	}
+	return score
+}
+
+func scoreFromTT(score, ply int) int {
+	if score > scoreMate-maxPly {
+		return score - ply
+	} else if score < -scoreMate+maxPly {
+		return score + ply
+	}
+	return score
+}
+
+// The result of a completed iteration of the search
+type SearchResult struct {
+	Depth    int
+	Score    int
+	BestMove Move
+	// the principal variation, starting with BestMove
+	PV []Move
+	// the 1-based number of the line when searching multiple lines
+	MultiPV int
+	// t_LowerBound if the iteration was interrupted, the unsearched moves could only raise the score
+	Bound uint8
+	// the deepest ply reached, including the quiescence search
+	SelDepth int
+	Nodes    uint64
+	Time     time.Duration
+	// the permille of the transposition table in use
+	Hashfull int
+}
+
+// The limits of a search as sent by the GUI with the go command, zero values mean no limit
+type SearchLimits struct {
+	Depth    int
+	Nodes    uint64
+	MoveTime time.Duration
+	// the clocks of both sides, the time manager splits them between the remaining moves.
+	// A clock that ran out is sent as 0, the flags tell it apart from a clock that wasn't sent
+	WhiteTime, BlackTime           time.Duration
+	WhiteTimeSet, BlackTimeSet     bool
+	WhiteIncrement, BlackIncrement time.Duration
+	MovesToGo                      int
+	// search for a mate in this many moves
+	Mate int
+	// the GUI decides when the search ends, see UCI.goCommand
+	Infinite bool
+	// searching on the opponent's time, the time limits apply from the ponderhit
+	Ponder bool
+}
+
+type Searcher struct {
+	board  *Board
+	limits SearchLimits
+	tm     timeManager
+	// set from another goroutine to abort the search, read atomically
+	stopped int32
+	// the time of the ponderhit in nanoseconds, set from another goroutine
+	ponderHitTime int64
+	ponderHit     bool
+	nodes         uint64
+	ply           int
+	selDepth      int
+	// the root moves searched in the current iteration and the best score among them
+	rootMovesSearched int
+	rootScore         int
+	// triangular table of the principal variation, see https://www.chessprogramming.org/Triangular_PV-Table
+	pvTable  [maxPly][maxPly]Move
+	pvLength [maxPly]int
+	// the best moves of the previous lines of a multipv iteration
+	excludedRootMoves []Move
+	// the number of best lines searched, at least 1
+	MultiPV int
+	// the number of threads searching, at least 1
+	Threads int
+	// called after every completed iteration, and for every line of it with MultiPV, may be nil
+	OnIteration func(SearchResult)
+	// called before searching each move at the root with its 1-based number, may be nil
+	OnRootMove func(depth int, move Move, number int)
+}
+
+func NewSearcher(board *Board) *Searcher {
+	if board.tt == nil {
+		board.tt = NewTranspositionTable(DefaultHashSizeMB)
+	}
+	return &Searcher{board: board}
+}
+
+// Aborts the search, the result of the last completed iteration is returned. Safe to call from another goroutine
+func (s *Searcher) Stop() {
+	atomic.StoreInt32(&s.stopped, 1)
+}
+
+// Switches a ponder search to a normal search with the time limits starting now. Safe to call from another goroutine
+func (s *Searcher) PonderHit() {
+	atomic.StoreInt64(&s.ponderHitTime, time.Now().UnixNano())
+}
+
+// whether the search is still pondering, starts the clock once the ponderhit arrives
+func (s *Searcher) pondering() bool {
+	if !s.limits.Ponder || s.ponderHit {
+		return false
+	}
+	hit := atomic.LoadInt64(&s.ponderHitTime)
+	if hit == 0 {
+		return true
+	}
+	s.ponderHit = true
+	s.tm.start = time.Unix(0, hit)
+	return false
+}
+
+// Returns the time since the search started, or since the ponderhit
+func (s *Searcher) Elapsed() time.Duration {
+	return s.tm.elapsed()
+}
+
+func (s *Searcher) shouldStop() bool {
+	return atomic.LoadInt32(&s.stopped) != 0
+}
+
+// Searches the position to the given depth, deepening one ply at a time.
+// BestMove is NullMove only if there are no legal moves
+func (s *Searcher) Search(maxDepth int) SearchResult {
+	return s.SearchWithLimits(SearchLimits{Depth: maxDepth})
+}
+
+// Searches the position deepening one ply at a time until one of the limits is reached or the search is stopped.
+// BestMove is NullMove only if there are no legal moves
+func (s *Searcher) SearchWithLimits(limits SearchLimits) SearchResult {
+	s.nodes = 0
+	s.limits = limits
+	s.tm = newTimeManager(limits, s.board.nextColor)
+	var result SearchResult
+	moves := s.board.GenerateLegalMoves()
+	if len(moves) == 0 {
+		if s.board.InCheck() {
+			result.Score = -scoreMate
+		}
+		return result
+	}
+	// a usable move in case the first iteration is stopped
+	result.BestMove = moves[0]
+	result.PV = []Move{moves[0]}
+	maxDepth := maxPly - 1
+	if limits.Depth > 0 && limits.Depth < maxDepth {
+		maxDepth = limits.Depth
+	}
+	// a mate in n moves is at most 2n-1 plies deep
+	if limits.Mate > 0 && 2*limits.Mate-1 < maxDepth {
+		maxDepth = 2*limits.Mate - 1
+	}
+	lines := s.MultiPV
+	if lines < 1 {
+		lines = 1
+	} else if lines > len(moves) {
+		lines = len(moves)
+	}
+	stopHelpers := s.startHelpers(maxDepth)
+	defer stopHelpers()
+	for depth := 1; depth <= maxDepth; depth++ {
+		// every line searches the root without the best moves of the previous lines
+		s.excludedRootMoves = s.excludedRootMoves[:0]
+		var best SearchResult
+		for line := 1; line <= lines; line++ {
+			s.ply = 0
+			s.selDepth = 0
+			s.rootMovesSearched = 0
+			score := s.negamax(-scoreInfinity, scoreInfinity, depth)
+			if s.shouldStop() {
+				if line > 1 {
+					// the first line of the iteration is complete
+					return best
+				}
+				// the moves searched so far are searched deeper than in the last iteration, the best one
+				// is at least as good as its score. The previous best move is searched first
+				if s.rootMovesSearched > 0 {
+					result = s.iterationResult(depth, s.rootScore)
+					result.Bound = t_LowerBound
+					if s.OnIteration != nil {
+						s.OnIteration(result)
+					}
+				}
+				return result
+			}
+			lineResult := s.iterationResult(depth, score)
+			lineResult.MultiPV = line
+			if s.OnIteration != nil {
+				s.OnIteration(lineResult)
+			}
+			if line == 1 {
+				best = lineResult
+			}
+			s.excludedRootMoves = append(s.excludedRootMoves, lineResult.BestMove)
+		}
+		previous := result
+		result = best
+		if isMateScore(result.Score) && scoreMate-abs(result.Score) <= depth {
+			// the shortest mate has been found
+			break
+		}
+		if depth > 1 {
+			s.tm.update(previous, result)
+		}
+		if !s.pondering() && s.tm.softLimitReached() {
+			break
+		}
+	}
+	return result
+}
+
+// Lazy SMP, see https://www.chessprogramming.org/Lazy_SMP
+// the helpers search copies of the board without limits, sharing the transposition table,
+// so the main search finds more cutoffs. Returns a function stopping them
+func (s *Searcher) startHelpers(maxDepth int) func() {
+	if s.Threads <= 1 {
+		return func() {}
+	}
+	var wg sync.WaitGroup
+	helpers := make([]*Searcher, s.Threads-1)
+	for i := range helpers {
+		helper := &Searcher{board: s.board.Clone()}
+		helpers[i] = helper
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			helper.SearchWithLimits(SearchLimits{Depth: maxDepth})
+		}()
+	}
+	return func() {
+		for _, helper := range helpers {
+			helper.Stop()
+		}
+		wg.Wait()
+	}
+}
+
+func (s *Searcher) excludedRootMove(move Move) bool {
+	for _, excluded := range s.excludedRootMoves {
+		if move == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Searcher) iterationResult(depth, score int) SearchResult {
+	return SearchResult{
+		Depth:    depth,
+		Score:    score,
+		BestMove: s.pvTable[0][0],
+		PV:       s.extendPV(append([]Move(nil), s.pvTable[0][:s.pvLength[0]]...), depth),
+		SelDepth: s.selDepth,
+		Nodes:    s.nodes,
+		Time:     s.Elapsed(),
+		Hashfull: s.board.tt.Hashfull(),
+	}
+}
+
+// the principal variation stops at transposition table cutoffs, it's continued with the
+// best moves stored in the table up to the depth of the iteration
+func (s *Searcher) extendPV(pv []Move, depth int) []Move {
+	board := s.board
+	for _, move := range pv {
+		board.MakeMove(move)
+	}
+	for len(pv) < depth {
+		entry, ok := board.tt.probe(board.zobristHash)
+		if !ok || entry.move == NullMove {
+			break
+		}
+		legal := false
+		for _, move := range board.GenerateLegalMoves() {
+			if move == entry.move {
+				legal = true
+				break
+			}
+		}
+		if !legal {
+			break
+		}
+		board.MakeMove(entry.move)
+		pv = append(pv, entry.move)
+	}
+	for range pv {
+		board.UnmakeMove()
+	}
+	return pv
+}
+
+// nodes searched between looks at the clock, few enough to stop within a couple of
+// milliseconds on a slow machine or a race enabled build, where nodes are much slower
+const timeCheckInterval = 64
+
+// aborts the search once the node count or the hard time limit is reached
+func (s *Searcher) checkLimits() {
+	if s.pondering() {
+		return
+	}
+	if s.limits.Nodes != 0 && s.nodes >= s.limits.Nodes {
+		s.Stop()
+	}
+	if s.nodes%timeCheckInterval == 0 && s.tm.hardLimitReached() {
+		s.Stop()
+	}
+}
+
+// Returns the score of the position searched to the given depth from white's point of view,
+// math.MaxInt32 if white mates and math.MinInt32 if black mates
 func (board *Board) NewRoot(depth int) int {
-	return board.alphaBetaMax(math.MinInt32, math.MaxInt32, depth)
+	result := NewSearcher(board).Search(depth)
+	score := result.Score
+	if board.nextColor == c_Black {
+		score = -score
+	}
+	if isMateScore(score) {
+		if score > 0 {
+			return math.MaxInt32
+		}
+		return math.MinInt32
+	}
+	return score
 }
 
-func (board *Board) alphaBetaMax(alpha, beta, depthLeft int) int {
+// orders the moves as the best move from a previous search of the position, captures that don't lose
+// material by most valuable victim, quiet moves and then losing captures
+func (board *Board) orderMoves(moves []Move, hashMove Move) {
+	scores := make([]int, len(moves))
+	for i, move := range moves {
+		switch {
+		case move == hashMove:
+			scores[i] = math.MaxInt32
+		case move.IsCapture() && board.SEE(move) < 0:
+			scores[i] = -1
+		case move.IsCapture() || move.IsPromotion():
+			scores[i] = 1 + board.capturedPower(move)*8 - board.pieceAt(move.From())%6
+		}
+	}
+	sort.Stable(movesByScore{moves, scores})
+}
+
+type movesByScore struct {
+	moves  []Move
+	scores []int
+}
+
+func (m movesByScore) Len() int           { return len(m.moves) }
+func (m movesByScore) Less(i, j int) bool { return m.scores[i] > m.scores[j] }
+func (m movesByScore) Swap(i, j int) {
+	m.moves[i], m.moves[j] = m.moves[j], m.moves[i]
+	m.scores[i], m.scores[j] = m.scores[j], m.scores[i]
+}
+
+func (s *Searcher) negamax(alpha, beta, depthLeft int) int {
+	board := s.board
+	s.pvLength[s.ply] = s.ply
+	// the root is searched even if it's a draw, since a move is still needed
+	if s.ply > 0 && (board.isRepetition(s.ply) || board.IsFiftyMoveDraw()) {
+		return 0
+	}
 	if depthLeft == 0 {
-		return board.evaluate()
+		return s.quiescence(alpha, beta)
+	}
+	if s.shouldStop() {
+		return 0
+	}
+	s.nodes++
+	s.checkLimits()
+	if s.ply > s.selDepth {
+		s.selDepth = s.ply
 	}
-	for i := 0; i < 20; i++ { // (all moves)
-		//board.makeMove()
-		score := board.alphaBetaMin(alpha, beta, depthLeft-1)
-		//board.unmakeMove()
+	hashMove := NullMove
+	if entry, ok := board.tt.probe(board.zobristHash); ok {
+		hashMove = entry.move
+		// no cutoffs at the root, where the move and principal variation are needed
+		if s.ply > 0 && int(entry.depth) >= depthLeft {
+			score := scoreFromTT(int(entry.score), s.ply)
+			switch {
+			case entry.bound == t_Exact:
+				return score
+			case entry.bound == t_LowerBound && score >= beta:
+				return beta
+			case entry.bound == t_UpperBound && score <= alpha:
+				return alpha
+			}
+		}
+	}
+	moves := board.GenerateLegalMoves()
+	if len(moves) == 0 {
+		// checkmate or stalemate
+		if board.InCheck() {
+			return -scoreMate + s.ply
+		}
+		return 0
+	}
+	if s.ply >= maxPly-1 {
+		return s.evaluate()
+	}
+	board.orderMoves(moves, hashMove)
+	bound := uint8(t_UpperBound)
+	bestMove := NullMove
+	for i, move := range moves {
+		if s.ply == 0 {
+			if s.excludedRootMove(move) {
+				continue
+			}
+			if s.OnRootMove != nil {
+				s.OnRootMove(depthLeft, move, i+1)
+			}
+		}
+		board.MakeMove(move)
+		s.ply++
+		score := -s.negamax(-beta, -alpha, depthLeft-1)
+		s.ply--
+		board.UnmakeMove()
+		if s.shouldStop() {
+			return 0
+		}
+		if s.ply == 0 {
+			s.rootMovesSearched++
+		}
 		if score >= beta {
+			board.tt.store(board.zobristHash, depthLeft, t_LowerBound, scoreToTT(beta, s.ply), move)
 			return beta
 		}
 		if score > alpha {
 			alpha = score
+			bound = t_Exact
+			bestMove = move
+			if s.ply == 0 {
+				s.rootScore = score
+			}
+			s.pvTable[s.ply][s.ply] = move
+			copy(s.pvTable[s.ply][s.ply+1:], s.pvTable[s.ply+1][s.ply+1:s.pvLength[s.ply+1]])
+			s.pvLength[s.ply] = s.pvLength[s.ply+1]
 		}
 	}
+	// the score of a root without some of its moves isn't the score of the position
+	if s.ply > 0 || len(s.excludedRootMoves) == 0 {
+		board.tt.store(board.zobristHash, depthLeft, bound, scoreToTT(alpha, s.ply), bestMove)
+	}
 	return alpha
 }
 
-func (board *Board) alphaBetaMin(alpha, beta, depthLeft int) int {
-	if depthLeft == 0 {
-		return board.evaluate()
+// margin over the captured piece for positional gains, captures that can't raise alpha even with it are skipped
+const deltaMargin = 200
+
+// Searches captures and promotions until the position is quiet, so that the evaluation
+// isn't taken in the middle of an exchange, see https://www.chessprogramming.org/Quiescence_Search
+func (s *Searcher) quiescence(alpha, beta int) int {
+	board := s.board
+	s.pvLength[s.ply] = s.ply
+	if s.shouldStop() {
+		return 0
+	}
+	s.nodes++
+	s.checkLimits()
+	if s.ply > s.selDepth {
+		s.selDepth = s.ply
+	}
+	moves := board.GenerateLegalMoves()
+	inCheck := board.InCheck()
+	if len(moves) == 0 {
+		if inCheck {
+			return -scoreMate + s.ply
+		}
+		return 0
+	}
+	standPat := s.evaluate()
+	if s.ply >= maxPly-1 {
+		return standPat
 	}
-	for i := 0; i < 20; i++ { // (all moves)
-		//board.makeMove()
-		score := board.alphaBetaMax(alpha, beta, depthLeft-1)
-		//board.unmakeMove()
-		if score <= alpha {
-			return alpha
+	// when in check every evasion is searched, since standing pat isn't an option
+	if !inCheck {
+		if standPat >= beta {
+			return beta
+		}
+		if standPat > alpha {
+			alpha = standPat
+		}
+		tactical := moves[:0]
+		for _, move := range moves {
+			if move.IsCapture() || move.IsPromotion() {
+				tactical = append(tactical, move)
+			}
+		}
+		moves = tactical
+	}
+	board.orderMoves(moves, NullMove)
+	for _, move := range moves {
+		if !inCheck {
+			if !move.IsPromotion() && standPat+board.capturedPower(move)+deltaMargin <= alpha {
+				continue
+			}
+			// captures that lose material can't be better than standing pat
+			if board.SEE(move) < 0 {
+				continue
+			}
+		}
+		board.MakeMove(move)
+		s.ply++
+		score := -s.quiescence(-beta, -alpha)
+		s.ply--
+		board.UnmakeMove()
+		if s.shouldStop() {
+			return 0
+		}
+		if score >= beta {
+			return beta
 		}
-		if score < beta {
-			beta = score
+		if score > alpha {
+			alpha = score
+			s.pvTable[s.ply][s.ply] = move
+			copy(s.pvTable[s.ply][s.ply+1:], s.pvTable[s.ply+1][s.ply+1:s.pvLength[s.ply+1]])
+			s.pvLength[s.ply] = s.pvLength[s.ply+1]
 		}
 	}
-	return beta
+	return alpha
+}
+
+// returns the value of the piece captured by the move, 0 if it isn't a capture
+func (board *Board) capturedPower(m Move) int {
+	if m.flags() == m_EnPassant {
+		return piecePower[p_Pawn]
+	}
+	if !m.IsCapture() {
+		return 0
+	}
+	return piecePower[board.pieceAt(m.To())%6]
+}
+
+// the static evaluation from the point of view of the side to move
+func (s *Searcher) evaluate() int {
+	if s.board.nextColor == c_Black {
+		return -s.board.evaluate()
+	}
+	return s.board.evaluate()
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
 }
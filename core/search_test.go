@@ -2,7 +2,218 @@ package core
 
 import "testing"
 
+func TestSearchMate(t *testing.T) {
+	type testCase struct {
+		fen      string
+		depth    int
+		bestMove string
+		score    int
+	}
+	testCases := []testCase{
+		// Back rank mate
+		{"6k1/5ppp/8/8/8/8/5PPP/R5K1 w - - 0 1", 3, "a1a8", scoreMate - 1},
+		// Rook roller, mate in two
+		{"7k/8/8/8/8/8/R7/1R4K1 w - - 0 1", 4, "b1b7", scoreMate - 3},
+		// Black gets mated whatever it plays
+		{"7k/1R6/8/8/8/8/8/R5K1 b - - 0 1", 3, "h8g8", -scoreMate + 2},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		result := NewSearcher(&board).Search(test.depth)
+		if result.BestMove.String() != test.bestMove || result.Score != test.score {
+			t.Errorf("\nSearch failed for %s\nExpected:%s %d\n     Got:%s %d", test.fen, test.bestMove, test.score, result.BestMove, result.Score)
+		}
+	}
+}
+
+func TestQuiescence(t *testing.T) {
+	type testCase struct {
+		fen      string
+		bestMove string
+		score    int
+	}
+	// Searched to depth 1, the exchanges after the first move are left to the quiescence search
+	testCases := []testCase{
+		// The hanging rook is taken
+		{"4k3/8/8/3r4/8/2N5/8/4K3 w - - 0 1", "c3d5", 300},
+		// The pawn is defended, so taking it loses the queen
+		{"4k3/8/4p3/3p4/8/8/3Q4/4K3 w - - 0 1", "", 700},
+		// Doubled rooks win the pawn after the exchange
+		{"3r2k1/8/8/3p4/8/8/3R4/3R2K1 w - - 0 1", "d2d5", 500},
+		// Black recaptures after the queen takes
+		{"3rk3/8/8/3p4/8/8/3Q4/4K3 w - - 0 1", "", 300},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		result := NewSearcher(&board).Search(1)
+		if result.Score != test.score || (test.bestMove != "" && result.BestMove.String() != test.bestMove) ||
+			(test.bestMove == "" && result.BestMove.String() == "d2d5") {
+			t.Errorf("\nQuiescence failed for %s\nExpected:%s %d\n     Got:%s %d", test.fen, test.bestMove, test.score, result.BestMove, result.Score)
+		}
+	}
+}
+
+func TestSearchPrincipalVariation(t *testing.T) {
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	fen := board.GetFen()
+	searcher := NewSearcher(&board)
+	iterations := 0
+	searcher.OnIteration = func(result SearchResult) {
+		iterations++
+		if result.Depth != iterations {
+			t.Errorf("Iteration %d reported depth %d", iterations, result.Depth)
+		}
+	}
+	result := searcher.Search(4)
+	if iterations != 4 || result.Depth != 4 {
+		t.Errorf("Expected 4 iterations, got %d", iterations)
+	}
+	if len(result.PV) == 0 || result.PV[0] != result.BestMove {
+		t.Fatalf("Principal variation %v doesn't start with the best move %s", result.PV, result.BestMove)
+	}
+	// the principal variation must be playable
+	for _, move := range result.PV {
+		legal := false
+		for _, legalMove := range board.GenerateLegalMoves() {
+			if legalMove == move {
+				legal = true
+			}
+		}
+		if !legal {
+			t.Fatalf("Illegal move %s in principal variation %v", move, result.PV)
+		}
+		board.MakeMove(move)
+	}
+	for range result.PV {
+		board.UnmakeMove()
+	}
+	if board.GetFen() != fen {
+		t.Errorf("Search changed the position\nExpected:%s\n     Got:%s", fen, board.GetFen())
+	}
+	// searching again hits the transposition table right away, the variation is continued from it
+	searcher = NewSearcher(&board)
+	searcher.OnIteration = func(result SearchResult) {
+		if len(result.PV) < result.Depth {
+			t.Errorf("Principal variation %v is shorter than depth %d", result.PV, result.Depth)
+		}
+	}
+	searcher.Search(3)
+	if board.GetFen() != fen {
+		t.Errorf("Search changed the position\nExpected:%s\n     Got:%s", fen, board.GetFen())
+	}
+}
+
+func TestSearchStop(t *testing.T) {
+	var board Board
+	board.LoadFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	searcher := NewSearcher(&board)
+	searcher.Stop()
+	result := searcher.Search(10)
+	if result.BestMove == NullMove || result.Depth != 0 {
+		t.Errorf("Stopped search didn't return a usable move: %+v", result)
+	}
+
+	var stalemate Board
+	stalemate.LoadFen("7k/8/5KQ1/8/8/8/8/8 b - - 0 1")
+	result = NewSearcher(&stalemate).Search(3)
+	if result.BestMove != NullMove || result.Score != 0 {
+		t.Errorf("Expected no move in stalemate: %+v", result)
+	}
+}
+
+func TestSearchLimits(t *testing.T) {
+	const kiwipete = "r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1"
+	// the search may count a few nodes past the limit before it unwinds
+	const margin = timeCheckInterval
+	var board Board
+	board.LoadFen(kiwipete)
+	result := NewSearcher(&board).SearchWithLimits(SearchLimits{Nodes: 5000})
+	if result.Nodes > 5000+margin || result.BestMove == NullMove {
+		t.Errorf("Node limited search returned %+v", result)
+	}
+
+	// an interrupted iteration is used once its first move is searched, with a lower bound score.
+	// The limit falls inside the third iteration of the same search without a limit
+	var full Board
+	full.LoadFen(kiwipete)
+	searcher := NewSearcher(&full)
+	var iterationNodes []uint64
+	searcher.OnIteration = func(result SearchResult) { iterationNodes = append(iterationNodes, result.Nodes) }
+	searcher.Search(3)
+	limit := (iterationNodes[1] + iterationNodes[2]) / 2
+	var interrupted Board
+	interrupted.LoadFen(kiwipete)
+	searcher = NewSearcher(&interrupted)
+	var reported []SearchResult
+	searcher.OnIteration = func(result SearchResult) { reported = append(reported, result) }
+	result = searcher.SearchWithLimits(SearchLimits{Nodes: limit})
+	if result.Nodes > limit+margin || len(reported) < 2 || len(reported) > 3 || result.Depth != len(reported) {
+		t.Fatalf("Search limited to %d nodes returned %+v after %+v", limit, result, reported)
+	}
+	if reported[1].Bound != t_Exact || len(reported) == 3 && result.Bound != t_LowerBound {
+		t.Errorf("Interrupted iteration not reported as a lower bound: %+v", reported)
+	}
+
+	var mate Board
+	mate.LoadFen("7k/8/8/8/8/8/R7/1R4K1 w - - 0 1")
+	result = NewSearcher(&mate).SearchWithLimits(SearchLimits{Mate: 2})
+	if result.Score != scoreMate-3 || result.Depth > 3 {
+		t.Errorf("Mate in 2 search returned %+v", result)
+	}
+}
+
+func TestSearchMultiPV(t *testing.T) {
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	searcher := NewSearcher(&board)
+	searcher.MultiPV = 3
+	var lines []SearchResult
+	searcher.OnIteration = func(result SearchResult) {
+		if result.Depth == 3 {
+			lines = append(lines, result)
+		}
+	}
+	result := searcher.Search(3)
+	if len(lines) != 3 || result.BestMove != lines[0].BestMove {
+		t.Fatalf("Expected 3 lines with the best first, got %+v", lines)
+	}
+	for i, line := range lines {
+		if line.MultiPV != i+1 || (i > 0 && (line.Score > lines[i-1].Score || line.BestMove == lines[i-1].BestMove)) {
+			t.Errorf("Lines not distinct and sorted: %+v", lines)
+		}
+	}
+}
+
+func TestSearchThreads(t *testing.T) {
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	fen := board.GetFen()
+	searcher := NewSearcher(&board)
+	searcher.Threads = 4
+	result := searcher.Search(4)
+	if result.Depth != 4 || result.BestMove == NullMove {
+		t.Errorf("Search with threads returned %+v", result)
+	}
+	if board.GetFen() != fen {
+		t.Errorf("Search changed the position: %s", board.GetFen())
+	}
+
+	var mate Board
+	mate.LoadFen("7k/8/8/8/8/8/R7/1R4K1 w - - 0 1")
+	searcher = NewSearcher(&mate)
+	searcher.Threads = 2
+	if result = searcher.Search(5); result.Score != scoreMate-3 {
+		t.Errorf("Mate not found with threads: %+v", result)
+	}
+}
+
 func BenchmarkDepthFunc(b *testing.B) {
 	var board Board
-	board.NewRoot(13)
+	board.LoadFen("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1")
+	for i := 0; i < b.N; i++ {
+		board.NewRoot(4)
+	}
 }
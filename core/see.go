@@ -0,0 +1,73 @@
+package core
+
+import "math/bits"
+
+// Static exchange evaluation, see https://www.chessprogramming.org/SEE_-_The_Swap_Algorithm
+
+// the king is worth more than everything else, but small enough not to overflow the swap list
+var seePower [6]int = [6]int{piecePower[p_Pawn], piecePower[p_Knight], piecePower[p_Bishop], piecePower[p_Rook], piecePower[p_Queen], 20000}
+
+// Returns the material won by the side to move after the exchange started by the move,
+// with both sides capturing on the target square with their least valuable piece and
+// stopping whenever continuing would lose material. Pins are not taken into account.
+// Positive for winning captures, negative for losing ones and moves to squares where the piece is lost
+func (board *Board) SEE(m Move) int {
+	var gain [32]int
+	to := m.To()
+	fromBit := uint64(1) << m.From()
+	occupancy := ^board.emptySquares
+	attacker := board.pieceAt(m.From()) % 6
+	switch {
+	case m.flags() == m_EnPassant:
+		gain[0] = seePower[p_Pawn]
+		// the captured pawn is next to the capturing one
+		occupancy &^= uint64(1) << (int(m.From())&0x38 | int(to)&7)
+	case m.IsCapture():
+		gain[0] = seePower[board.pieceAt(to)%6]
+	}
+	if m.IsPromotion() {
+		gain[0] += seePower[m.PromotionPiece()] - seePower[p_Pawn]
+		attacker = m.PromotionPiece()
+	}
+	rooksQueens := board.whiteRooks | board.blackRooks | board.whiteQueens | board.blackQueens
+	bishopsQueens := board.whiteBishops | board.blackBishops | board.whiteQueens | board.blackQueens
+	attackers := board.AttackersTo(int(to), occupancy)
+	side := board.nextColor
+	depth := 0
+	for {
+		depth++
+		side = c_Black - side
+		// the score if the piece that just captured gets taken
+		gain[depth] = seePower[attacker] - gain[depth-1]
+		if max(-gain[depth-1], gain[depth]) < 0 {
+			break
+		}
+		occupancy ^= fromBit
+		// sliders behind the piece that captured join the exchange
+		attackers |= (rookAttacks(int(to), occupancy) & rooksQueens) | (bishopAttacks(int(to), occupancy) & bishopsQueens)
+		attackers &= occupancy
+		fromBit = 0
+		for piece := p_Pawn; piece <= p_King; piece++ {
+			candidates := attackers & *board.PieceBBmap[side+piece]
+			if candidates != 0 {
+				fromBit = uint64(1) << bits.TrailingZeros64(candidates)
+				attacker = piece
+				break
+			}
+		}
+		if fromBit == 0 {
+			break
+		}
+	}
+	for depth--; depth > 0; depth-- {
+		gain[depth-1] = -max(-gain[depth-1], gain[depth])
+	}
+	return gain[0]
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
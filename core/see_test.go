@@ -0,0 +1,49 @@
+package core
+
+import "testing"
+
+func TestSEE(t *testing.T) {
+	type testCase struct {
+		fen      string
+		move     string
+		expected int
+	}
+	testCases := []testCase{
+		// Undefended pawn
+		{"1k1r4/1pp4p/p7/4p3/8/P5P1/1PP4P/2K1R3 w - - 0 1", "e1e5", 100},
+		// Exchange with x-rays on both sides, the knight is lost for the pawn
+		{"1k1r3q/1ppn3p/p4b2/4p3/8/P2N2P1/1PP1R1BP/2K1Q3 w - - 0 1", "d3e5", -200},
+		// The rook behind wins the pawn
+		{"4r1k1/8/8/4p3/8/8/4R3/4R1K1 w - - 0 1", "e2e5", 100},
+		// Defended by a pawn
+		{"4k3/8/4p3/3p4/8/8/3Q4/4K3 w - - 0 1", "d2d5", -800},
+		{"4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1", "e5d6", 100},
+		// Quiet move to an attacked square
+		{"4k3/8/2p5/8/8/8/8/3QK3 w - - 0 1", "d1d5", -900},
+		{"4k3/8/8/8/8/8/8/3QK3 w - - 0 1", "d1d5", 0},
+		{"4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", 800},
+		// The new queen is taken
+		{"r3k3/1P6/8/8/8/8/8/4K3 w - - 0 1", "b7b8q", -100},
+		{"4k3/3q4/8/8/8/8/3Q4/3RK3 b - - 0 1", "d7d2", 0},
+		// The king can't recapture a defended piece
+		{"3rk3/3q4/8/8/8/8/3N4/4K3 b - - 0 1", "d7d2", 300},
+		{"4k3/8/8/8/8/8/3q4/4K3 w - - 0 1", "e1d2", 900},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		found := false
+		for _, move := range board.GenerateLegalMoves() {
+			if move.String() != test.move {
+				continue
+			}
+			found = true
+			if see := board.SEE(move); see != test.expected {
+				t.Errorf("\nSEE failed for %s in %s\nExpected:%d\n     Got:%d", test.move, test.fen, test.expected, see)
+			}
+		}
+		if !found {
+			t.Errorf("Move %s not found in %s", test.move, test.fen)
+		}
+	}
+}
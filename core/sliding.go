@@ -2,17 +2,52 @@ package core
 
 import "math/bits"
 
-func (board *Board) generateBishopMoves() uint64 {
-	return 0
+func (board *Board) generateBishopMoves(square int) uint64 {
+	bb := bishopAttacks(square, ^board.emptySquares)
+	bb &= (^(*board.ColorBBmap[board.nextColor]))
+	return bb
 }
 
 func (board *Board) generateRookMoves(square int) uint64 {
-	bb := getHorizontalSlide(square, ^board.emptySquares)
-	bb |= getVerticalSlide(square, ^board.emptySquares)
+	bb := rookAttacks(square, ^board.emptySquares)
 	bb &= (^(*board.ColorBBmap[board.nextColor]))
 	return bb
 }
 
+func (board *Board) generateQueenMoves(square int) uint64 {
+	return board.generateRookMoves(square) | board.generateBishopMoves(square)
+}
+
+// Common interface for the different ways of looking up sliding piece attacks
+type sliderAttacks interface {
+	rookAttacks(square int, occupancy uint64) uint64
+	bishopAttacks(square int, occupancy uint64) uint64
+}
+
+// the backend used by move generation, magic bitboards benchmark faster (see BenchmarkSliders)
+var sliders sliderAttacks = magicSliders{}
+
+// returns the squares attacked by a rook on square, given the occupancy
+func rookAttacks(square int, occupancy uint64) uint64 {
+	return sliders.rookAttacks(square, occupancy)
+}
+
+// returns the squares attacked by a bishop on square, given the occupancy
+func bishopAttacks(square int, occupancy uint64) uint64 {
+	return sliders.bishopAttacks(square, occupancy)
+}
+
+// Rotated bitboards, https://www.chessprogramming.org/Rotated_Bitboards
+type rotatedSliders struct{}
+
+func (rotatedSliders) rookAttacks(square int, occupancy uint64) uint64 {
+	return getHorizontalSlide(square, occupancy) | getVerticalSlide(square, occupancy)
+}
+
+func (rotatedSliders) bishopAttacks(square int, occupancy uint64) uint64 {
+	return getDiagonalSlide(square, occupancy) | getAntiDiagonalSlide(square, occupancy)
+}
+
 func getHorizontalSlide(square int, bb uint64) uint64 {
 	rowIndex := square & 0xf8
 	curRow := (bb >> (uint64(rowIndex))) & 0xff
@@ -26,13 +61,24 @@ func getVerticalSlide(square int, bb uint64) uint64 {
 	return slidingVertical[square][curCol]
 }
 
+// rotate45CW maps every a1-h8 diagonal onto a rank, keeping the files of its squares,
+// so the diagonal occupancy can be read like a rank and used with the same kind of table
 func getDiagonalOccupancy(square int, bb uint64) uint8 {
 	var translation int = int(diagonalTranslation[square])
-	bbd := diagonalBitboards[translation]
-	var occ uint8 = uint8(bits.RotateLeft64(rotate45CW(bbd), 8*translation)) & diagonalMasks[translation]
+	var occ uint8 = uint8(bits.RotateLeft64(rotate45CW(bb), 8*translation)) & diagonalMasks[translation]
 	return occ
 }
 
+func getDiagonalSlide(square int, bb uint64) uint64 {
+	return slidingDiagonal[square][getDiagonalOccupancy(square, bb)]
+}
+
+// mirroring the board turns the a8-h1 anti-diagonals into a1-h8 diagonals
+func getAntiDiagonalSlide(square int, bb uint64) uint64 {
+	mirrored := square ^ 7
+	return slidingAntiDiagonal[square][getDiagonalOccupancy(mirrored, flipHorizontally(bb))]
+}
+
 func generateSliding() {
 	for i := 0; i < 8; i++ {
 		// mask to remove our own piece
@@ -65,9 +111,18 @@ func generateSliding() {
 		}
 	}
 	// Diagonal generation
-	for d := 0; d <= 7; d++ {
+	for square := 0; square < 64; square++ {
+		translation := diagonalTranslation[square]
+		// the rank the diagonal is on after rotate45CW
+		rotatedRow := (8 - int(translation)) & 7
+		for occ := 0; occ < 256; occ++ {
+			row := slidingHorizontal[square&7][occ] & uint64(diagonalMasks[translation])
+			slidingDiagonal[square][occ] = unrotate45CW(row << (rotatedRow * 8))
+		}
+	}
+	for square := 0; square < 64; square++ {
 		for occ := 0; occ < 256; occ++ {
-			slidingDiagonal[d*9][occ] = unrotate45CW(slidingHorizontal[d][occ])
+			slidingAntiDiagonal[square][occ] = flipHorizontally(slidingDiagonal[square^7][occ])
 		}
 	}
 }
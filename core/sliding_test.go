@@ -2,6 +2,7 @@ package core
 
 import (
 	"math/bits"
+	"math/rand"
 	"testing"
 )
 
@@ -45,25 +46,27 @@ func TestDiagonalSliding(t *testing.T) {
 	testCases := []testCase{
 		// Test cases with fen and expected bitboard outcome
 		{"8/8/8/8/8/8/8/B7 w - - 0 1", 0x8040201008040200},
-		{"8/8/8/8/8/8/1B6/8 w - - 0 1", 0x8040201008040001},
-		{"8/8/8/8/8/2B5/8/8 w - - 0 1", 0x8040201008000201},
-		{"8/8/8/8/3B4/8/8/8 w - - 0 1", 0x8040201000040201},
-		{"8/8/8/4B3/8/8/8/8 w - - 0 1", 0x8040200008040201},
-		{"8/8/5B2/8/8/8/8/8 w - - 0 1", 0x8040001008040201},
-		{"8/6B1/8/8/8/8/8/8 w - - 0 1", 0x8000201008040201},
+		{"8/8/8/8/8/8/1B6/8 w - - 0 1", 0x8040201008050005},
+		{"8/8/8/8/8/2B5/8/8 w - - 0 1", 0x804020110a000a11},
+		{"8/8/8/8/3B4/8/8/8 w - - 0 1", 0x8041221400142241},
+		{"8/8/8/4B3/8/8/8/8 w - - 0 1", 0x8244280028448201},
+		{"8/8/5B2/8/8/8/8/8 w - - 0 1", 0x8850005088040201},
+		{"8/6B1/8/8/8/8/8/8 w - - 0 1", 0xa000a01008040201},
 		{"7B/8/8/8/8/8/8/8 w - - 0 1", 0x0040201008040201},
+		{"8/8/8/8/8/8/8/7B w - - 0 1", 0x0102040810204000},
 		// Test cases blocked by own pieces and enemy
+		{"8/8/8/8/3B4/8/1P6/8 w - - 0 1", 0x8041221400142040},
+		{"8/6p1/8/4P3/3B4/2p5/8/8 w - - 0 1", 0x0001020400142040},
 	}
 	generateSliding()
 	for _, test := range testCases {
 		var board Board
 		board.LoadFen(test.fen)
 		sq := bits.TrailingZeros64(*board.PieceBBmap[p_Bishop])
-		bb := slidingDiagonal[sq][^getDiagonalOccupancy(sq, ^board.emptySquares)]
-		// bb &= ^board.whiteSquares
+		bb := board.generateBishopMoves(sq)
 		if bb != test.expected {
 			DrawBitboard(t, bb)
-			DrawBitboard(t, test.expected)
+			t.Errorf("%s\nExpected:0x%016x\nGot     :0x%016x", test.fen, test.expected, bb)
 		}
 	}
 }
@@ -101,5 +104,102 @@ func TestBishopGetDiagonalOccupancy(t *testing.T) {
 }
 
 func TestGetDiagonal(t *testing.T) {
-	DrawBitboard(t, diagonalBitboards[diagonalTranslation[27]])
+	for sq := 0; sq < 64; sq++ {
+		diagonal := diagonalBitboards[diagonalTranslation[sq]]
+		if (diagonal & (uint64(1) << sq)) == 0 {
+			t.Errorf("Square %d not on its diagonal", sq)
+			DrawBitboard(t, diagonal)
+		}
+	}
+}
+
+// naive ray walking, used as a reference for the lookup tables
+func slidingReference(square int, occupancy uint64, directions [][2]int) uint64 {
+	var ret uint64
+	for _, dir := range directions {
+		x, y := square&7+dir[0], square>>3+dir[1]
+		for x >= 0 && x < 8 && y >= 0 && y < 8 {
+			bit := uint64(1) << (x + y*8)
+			ret |= bit
+			if (occupancy & bit) != 0 {
+				break
+			}
+			x += dir[0]
+			y += dir[1]
+		}
+	}
+	return ret
+}
+
+var rookDirections = [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+var bishopDirections = [][2]int{{1, 1}, {1, -1}, {-1, 1}, {-1, -1}}
+
+func randomOccupancies(count int) []uint64 {
+	rng := rand.New(rand.NewSource(1))
+	occupancies := []uint64{0, 0xffffffffffffffff}
+	for i := 0; i < count; i++ {
+		// sparse and dense occupancies
+		occupancies = append(occupancies, rng.Uint64()&rng.Uint64(), rng.Uint64()|rng.Uint64(), rng.Uint64())
+	}
+	return occupancies
+}
+
+func TestSlidingExhaustive(t *testing.T) {
+	var board Board
+	board.init()
+	occupancies := randomOccupancies(2000)
+	backends := map[string]sliderAttacks{"rotated": rotatedSliders{}, "magic": magicSliders{}}
+	for name, backend := range backends {
+		for sq := 0; sq < 64; sq++ {
+			for _, occ := range occupancies {
+				if got, expected := backend.bishopAttacks(sq, occ), slidingReference(sq, occ, bishopDirections); got != expected {
+					t.Fatalf("%s bishop attacks failed for square %d occupancy 0x%016x\nExpected:0x%016x\nGot     :0x%016x", name, sq, occ, expected, got)
+				}
+				if got, expected := backend.rookAttacks(sq, occ), slidingReference(sq, occ, rookDirections); got != expected {
+					t.Fatalf("%s rook attacks failed for square %d occupancy 0x%016x\nExpected:0x%016x\nGot     :0x%016x", name, sq, occ, expected, got)
+				}
+			}
+		}
+	}
+}
+
+func TestBishopMoveCountEmpty(t *testing.T) {
+	var board Board
+	board.init()
+	board.recalculateGeneralMaps()
+	for i := 0; i < 64; i++ {
+		res := bits.OnesCount64(board.generateBishopMoves(i))
+		if res != int(bishopMoveCountTable[i]) {
+			t.Errorf("Bishop move count failed for square %d", i)
+		}
+	}
+}
+
+func TestQueenSlide(t *testing.T) {
+	var board Board
+	board.LoadFen("8/8/8/3p4/2PQ3p/8/1p3P2/8 w - - 0 1")
+	bb := board.generateQueenMoves(27)
+	expected := slidingReference(27, ^board.emptySquares, append(rookDirections, bishopDirections...)) & ^board.whiteSquares
+	if bb != expected {
+		DrawBitboard(t, bb)
+		t.Errorf("Expected:0x%016x\nGot     :0x%016x", expected, bb)
+	}
+}
+
+func benchmarkSliders(b *testing.B, backend sliderAttacks) {
+	var board Board
+	board.init()
+	occupancies := randomOccupancies(64)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, occ := range occupancies {
+			backend.rookAttacks(j&63, occ)
+			backend.bishopAttacks(j&63, occ)
+		}
+	}
+}
+
+func BenchmarkSliders(b *testing.B) {
+	b.Run("rotated", func(b *testing.B) { benchmarkSliders(b, rotatedSliders{}) })
+	b.Run("magic", func(b *testing.B) { benchmarkSliders(b, magicSliders{}) })
 }
@@ -0,0 +1,47 @@
+package core
+
+// Whether the game is over and how, for the frontend and anything running matches
+
+type GameStatus int
+
+const (
+	StatusOngoing GameStatus = iota
+	StatusCheckmate
+	StatusStalemate
+	StatusInsufficientMaterial
+	StatusFiftyMoveRule
+	StatusRepetition
+)
+
+var gameStatusNames = [...]string{"ongoing", "checkmate", "stalemate", "insufficient material", "fifty-move rule", "threefold repetition"}
+
+func (status GameStatus) String() string {
+	return gameStatusNames[status]
+}
+
+func (status GameStatus) IsOver() bool {
+	return status != StatusOngoing
+}
+
+// Returns the state of the game with its result as written in PGN: 1-0, 0-1, 1/2-1/2, or * while it's ongoing.
+// Checkmate and stalemate take precedence over the other draws
+func (board *Board) Status() (GameStatus, string) {
+	if len(board.GenerateLegalMoves()) == 0 {
+		if !board.InCheck() {
+			return StatusStalemate, "1/2-1/2"
+		}
+		if board.nextColor == c_White {
+			return StatusCheckmate, "0-1"
+		}
+		return StatusCheckmate, "1-0"
+	}
+	switch {
+	case board.IsInsufficientMaterial():
+		return StatusInsufficientMaterial, "1/2-1/2"
+	case board.IsFiftyMoveDraw():
+		return StatusFiftyMoveRule, "1/2-1/2"
+	case board.IsThreefoldRepetition():
+		return StatusRepetition, "1/2-1/2"
+	}
+	return StatusOngoing, "*"
+}
@@ -0,0 +1,55 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	type testCase struct {
+		fen    string
+		status GameStatus
+		result string
+	}
+	testCases := []testCase{
+		{startPositionFen, StatusOngoing, "*"},
+		// fool's mate
+		{"rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3", StatusCheckmate, "0-1"},
+		{"R5k1/5ppp/8/8/8/8/8/6K1 b - - 1 40", StatusCheckmate, "1-0"},
+		{"7k/5Q2/6K1/8/8/8/8/8 b - - 0 60", StatusStalemate, "1/2-1/2"},
+		{"8/8/4k3/8/8/3K4/8/8 w - - 0 60", StatusInsufficientMaterial, "1/2-1/2"},
+		{"8/8/4k3/8/8/3K4/8/5B2 w - - 0 60", StatusInsufficientMaterial, "1/2-1/2"},
+		{"8/8/4k3/8/8/3K4/8/5n2 w - - 0 60", StatusInsufficientMaterial, "1/2-1/2"},
+		// bishops on f1 and c8, both light squares
+		{"2b5/8/4k3/8/8/3K4/8/5B2 w - - 0 60", StatusInsufficientMaterial, "1/2-1/2"},
+		{"2b5/8/4k3/8/8/3K4/8/4B3 w - - 0 60", StatusOngoing, "*"},
+		{"8/8/4k3/8/8/3K4/8/4NB2 w - - 0 60", StatusOngoing, "*"},
+		{"8/8/4k3/8/8/3K4/8/4NN2 w - - 0 60", StatusOngoing, "*"},
+		{"8/8/4k3/8/8/3K4/P7/8 w - - 0 60", StatusOngoing, "*"},
+		{"8/8/4k3/8/8/3K4/8/7R w - - 100 80", StatusFiftyMoveRule, "1/2-1/2"},
+		// a checkmate on the last of the fifty moves counts
+		{"R5k1/5ppp/8/8/8/8/8/6K1 b - - 100 80", StatusCheckmate, "1-0"},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		status, result := board.Status()
+		if status != test.status || result != test.result {
+			t.Errorf("%s\nExpected: %s %s\n     Got: %s %s", test.fen, test.status, test.result, status, result)
+		}
+		if status.IsOver() != (test.status != StatusOngoing) {
+			t.Errorf("%s: bad game over %t", test.fen, status.IsOver())
+		}
+	}
+
+	var board Board
+	board.LoadFen(startPositionFen)
+	playMoves(t, &board, strings.Fields("g1f3 g8f6 f3g1 f6g8 g1f3 g8f6 f3g1"))
+	if status, _ := board.Status(); status != StatusOngoing {
+		t.Errorf("Game over by %s before the third repetition", status)
+	}
+	playMoves(t, &board, []string{"f6g8"})
+	if status, result := board.Status(); status != StatusRepetition || result != "1/2-1/2" {
+		t.Errorf("Expected a draw by repetition, got %s %s", status, result)
+	}
+}
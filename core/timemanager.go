@@ -0,0 +1,99 @@
+package core
+
+import "time"
+
+// Time management, see https://www.chessprogramming.org/Time_Management
+// The soft limit is checked between iterations and may be extended when the search is unstable,
+// the hard limit aborts the search in the middle of an iteration
+
+// time kept in reserve for the communication with the GUI
+const moveOverhead = 30 * time.Millisecond
+
+// the hard limit when the clock is within the overhead or already out, the move is sent right away
+const emergencyTime = 5 * time.Millisecond
+
+// moves the remaining time is split between when the GUI doesn't send movestogo
+const defaultMovesToGo = 30
+
+type timeManager struct {
+	enabled bool
+	start   time.Time
+	soft    time.Duration
+	hard    time.Duration
+	// multiplies the soft limit, raised when the best move changes or the score drops
+	scale float64
+}
+
+func newTimeManager(limits SearchLimits, color int) timeManager {
+	tm := timeManager{start: time.Now(), scale: 1}
+	remaining, increment, clockSet := limits.WhiteTime, limits.WhiteIncrement, limits.WhiteTimeSet
+	if color == c_Black {
+		remaining, increment, clockSet = limits.BlackTime, limits.BlackIncrement, limits.BlackTimeSet
+	}
+	switch {
+	case limits.MoveTime > 0:
+		tm.enabled = true
+		tm.hard = reserveOverhead(limits.MoveTime)
+		tm.soft = tm.hard
+	case remaining > 0 || clockSet:
+		tm.enabled = true
+		if remaining <= moveOverhead {
+			tm.soft, tm.hard = emergencyTime, emergencyTime
+			break
+		}
+		movesToGo := limits.MovesToGo
+		if movesToGo <= 0 {
+			movesToGo = defaultMovesToGo
+		}
+		limit := reserveOverhead(remaining)
+		tm.soft = minDuration(remaining/time.Duration(movesToGo)+increment*3/4, limit)
+		tm.hard = minDuration(tm.soft*4, limit)
+	}
+	return tm
+}
+
+// leaves time for the overhead, or half of it if there's very little time
+func reserveOverhead(available time.Duration) time.Duration {
+	if available-moveOverhead < available/2 {
+		return available / 2
+	}
+	return available - moveOverhead
+}
+
+func (tm *timeManager) elapsed() time.Duration {
+	return time.Since(tm.start)
+}
+
+// whether another iteration should be started
+func (tm *timeManager) softLimitReached() bool {
+	if !tm.enabled {
+		return false
+	}
+	soft := minDuration(time.Duration(float64(tm.soft)*tm.scale), tm.hard)
+	return tm.elapsed() >= soft
+}
+
+func (tm *timeManager) hardLimitReached() bool {
+	return tm.enabled && tm.elapsed() >= tm.hard
+}
+
+// gives the search more time when the last iteration changed its mind or found a worse score,
+// since the position is harder than it looked
+func (tm *timeManager) update(previous, current SearchResult) {
+	tm.scale = 1
+	if current.BestMove != previous.BestMove {
+		tm.scale += 0.5
+	}
+	if drop := previous.Score - current.Score; drop >= 100 {
+		tm.scale += 1
+	} else if drop >= 30 {
+		tm.scale += 0.5
+	}
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
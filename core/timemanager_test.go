@@ -0,0 +1,91 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeAllocation(t *testing.T) {
+	ms := time.Millisecond
+	type testCase struct {
+		limits     SearchLimits
+		color      int
+		soft, hard time.Duration
+	}
+	testCases := []testCase{
+		{SearchLimits{MoveTime: 1000 * ms}, c_White, 970 * ms, 970 * ms},
+		{SearchLimits{WhiteTime: 60000 * ms, BlackTime: 1000 * ms}, c_White, 2000 * ms, 8000 * ms},
+		{SearchLimits{WhiteTime: 60000 * ms, BlackTime: 30000 * ms, BlackIncrement: 1000 * ms}, c_Black, 1750 * ms, 7000 * ms},
+		// the last move before the time control may use everything but the overhead
+		{SearchLimits{WhiteTime: 5000 * ms, MovesToGo: 1}, c_White, 4970 * ms, 4970 * ms},
+		// with almost no time left half of it is kept
+		{SearchLimits{WhiteTime: 40 * ms, MovesToGo: 1}, c_White, 20 * ms, 20 * ms},
+		// a clock within the overhead or out of time gets a move right away
+		{SearchLimits{WhiteTime: 30 * ms, WhiteTimeSet: true}, c_White, emergencyTime, emergencyTime},
+		{SearchLimits{WhiteTimeSet: true, BlackTime: 1000 * ms, BlackTimeSet: true}, c_White, emergencyTime, emergencyTime},
+		// the clock of the other side doesn't limit the search
+		{SearchLimits{WhiteTimeSet: true}, c_Black, 0, 0},
+		{SearchLimits{Depth: 5}, c_White, 0, 0},
+	}
+	for _, test := range testCases {
+		tm := newTimeManager(test.limits, test.color)
+		if tm.soft != test.soft || tm.hard != test.hard {
+			t.Errorf("\nBad time allocation for %+v\nExpected:%v %v\n     Got:%v %v", test.limits, test.soft, test.hard, tm.soft, tm.hard)
+		}
+		if tm.enabled != (test.hard != 0) {
+			t.Errorf("Time management enabled:%t for %+v", tm.enabled, test.limits)
+		}
+	}
+}
+
+func TestTimeExtension(t *testing.T) {
+	tm := newTimeManager(SearchLimits{WhiteTime: 60000 * time.Millisecond}, c_White)
+	previous := SearchResult{BestMove: newMove(12, 28, m_DoublePawnPush), Score: 50}
+	tm.update(previous, previous)
+	if tm.scale != 1 {
+		t.Errorf("Stable search extended: %f", tm.scale)
+	}
+	tm.update(previous, SearchResult{BestMove: newMove(11, 27, m_DoublePawnPush), Score: -100})
+	if tm.scale != 2.5 {
+		t.Errorf("Unstable search not extended: %f", tm.scale)
+	}
+}
+
+func TestSearchTimeLimits(t *testing.T) {
+	// the search stops at the hard limit, give or take the time to unwind it,
+	// which still leaves part of the overhead kept for the GUI
+	const margin = 20 * time.Millisecond
+	type testCase struct {
+		fen    string
+		limits SearchLimits
+		clock  time.Duration
+	}
+	testCases := []testCase{
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+			SearchLimits{MoveTime: 100 * time.Millisecond}, 100 * time.Millisecond},
+		{"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R b KQkq - 0 1",
+			SearchLimits{WhiteTime: time.Hour, BlackTime: 200 * time.Millisecond, MovesToGo: 1}, 200 * time.Millisecond},
+	}
+	for _, test := range testCases {
+		var board Board
+		board.LoadFen(test.fen)
+		hard := newTimeManager(test.limits, board.nextColor).hard
+		if hard+margin >= test.clock {
+			t.Fatalf("Hard limit %v leaves no margin on a %v clock", hard, test.clock)
+		}
+		start := time.Now()
+		result := NewSearcher(&board).SearchWithLimits(test.limits)
+		if elapsed := time.Since(start); elapsed > hard+margin || result.BestMove == NullMove {
+			t.Errorf("Search with %v on the clock took %v, hard limit %v, and returned %s", test.clock, elapsed, hard, result.BestMove)
+		}
+	}
+
+	// a flagged clock still limits the search, as sent with go wtime -20 btime 1000
+	var board Board
+	board.LoadFen("r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1")
+	start := time.Now()
+	result := NewSearcher(&board).SearchWithLimits(SearchLimits{WhiteTimeSet: true, BlackTime: time.Second, BlackTimeSet: true})
+	if elapsed := time.Since(start); elapsed > emergencyTime+margin || result.BestMove == NullMove {
+		t.Errorf("Search without time left took %v and returned %s", elapsed, result.BestMove)
+	}
+}
@@ -0,0 +1,153 @@
+package core
+
+import (
+	"math"
+	"sync/atomic"
+	"unsafe"
+)
+
+// Transposition table, see https://www.chessprogramming.org/Transposition_Table
+// Each bucket holds two entries, one only replaced by deeper searches and one always replaced.
+// The table is shared by the search threads without locking, entries are stored as the packed data
+// and the key xored with it, so an entry torn by concurrent writes doesn't match its key
+// https://www.chessprogramming.org/Shared_Hash_Table#Lock-less
+
+const (
+	t_Exact      = 0
+	t_LowerBound = 1 // the score failed high, the real score is at least this
+	t_UpperBound = 2 // the score failed low, the real score is at most this
+)
+
+const DefaultHashSizeMB = 16
+
+type ttEntry struct {
+	key   uint64
+	score int32
+	move  Move
+	depth int8
+	bound uint8
+}
+
+// an entry as stored in the table
+type ttSlot struct {
+	check uint64
+	data  uint64
+}
+
+// set in the data of every stored entry, an empty slot would otherwise match the key 0
+const ttUsed = uint64(1) << 63
+
+func (entry ttEntry) pack() uint64 {
+	return uint64(uint32(entry.score)) | uint64(entry.move)<<32 | uint64(uint8(entry.depth))<<48 | uint64(entry.bound)<<56 | ttUsed
+}
+
+// returns the entry in the slot, false if the slot is empty
+func (slot *ttSlot) load() (ttEntry, bool) {
+	data := atomic.LoadUint64(&slot.data)
+	if data&ttUsed == 0 {
+		return ttEntry{}, false
+	}
+	return ttEntry{
+		key:   atomic.LoadUint64(&slot.check) ^ data,
+		score: int32(uint32(data)),
+		move:  Move(data >> 32),
+		depth: int8(data >> 48),
+		bound: uint8(data>>56) & 0b11,
+	}, true
+}
+
+func (slot *ttSlot) save(entry ttEntry) {
+	data := entry.pack()
+	atomic.StoreUint64(&slot.data, data)
+	atomic.StoreUint64(&slot.check, entry.key^data)
+}
+
+type ttBucket struct {
+	depthPreferred ttSlot
+	alwaysReplace  ttSlot
+}
+
+type TranspositionTable struct {
+	buckets []ttBucket
+	mask    uint64
+}
+
+// Creates a table using at most sizeMB megabytes, rounded down to a power of two buckets
+func NewTranspositionTable(sizeMB int) *TranspositionTable {
+	tt := &TranspositionTable{}
+	tt.Resize(sizeMB)
+	return tt
+}
+
+// Resizes the table to at most sizeMB megabytes, clearing it
+func (tt *TranspositionTable) Resize(sizeMB int) {
+	count := uint64(sizeMB) * 1024 * 1024 / uint64(unsafe.Sizeof(ttBucket{}))
+	size := uint64(1)
+	for size*2 <= count {
+		size *= 2
+	}
+	tt.buckets = make([]ttBucket, size)
+	tt.mask = size - 1
+}
+
+func (tt *TranspositionTable) Clear() {
+	for i := range tt.buckets {
+		tt.buckets[i] = ttBucket{}
+	}
+}
+
+// Returns the permille of the table in use, estimated from the first buckets
+func (tt *TranspositionTable) Hashfull() int {
+	sample := len(tt.buckets)
+	if sample > 1000 {
+		sample = 1000
+	}
+	used := 0
+	for i := 0; i < sample; i++ {
+		bucket := &tt.buckets[i]
+		if atomic.LoadUint64(&bucket.depthPreferred.data)&ttUsed != 0 {
+			used++
+		}
+		if atomic.LoadUint64(&bucket.alwaysReplace.data)&ttUsed != 0 {
+			used++
+		}
+	}
+	return used * 1000 / (2 * sample)
+}
+
+func (tt *TranspositionTable) probe(key uint64) (ttEntry, bool) {
+	bucket := &tt.buckets[key&tt.mask]
+	if entry, ok := bucket.depthPreferred.load(); ok && entry.key == key {
+		return entry, true
+	}
+	if entry, ok := bucket.alwaysReplace.load(); ok && entry.key == key {
+		return entry, true
+	}
+	return ttEntry{}, false
+}
+
+func (tt *TranspositionTable) store(key uint64, depth int, bound uint8, score int, move Move) {
+	bucket := &tt.buckets[key&tt.mask]
+	// deeper searches than the entry holds are stored as the deepest it can
+	if depth > math.MaxInt8 {
+		depth = math.MaxInt8
+	}
+	entry := ttEntry{key: key, score: int32(score), move: move, depth: int8(depth), bound: bound}
+	preferred, used := bucket.depthPreferred.load()
+	samePosition := used && preferred.key == key
+	if samePosition || depth >= int(preferred.depth) {
+		if move == NullMove && samePosition {
+			// keep the move of a previous search of the position for move ordering
+			entry.move = preferred.move
+		}
+		if used && !samePosition {
+			bucket.alwaysReplace.save(preferred)
+		}
+		bucket.depthPreferred.save(entry)
+		return
+	}
+	if always, ok := bucket.alwaysReplace.load(); ok && move == NullMove && always.key == key {
+		entry.move = always.move
+	}
+	bucket.alwaysReplace.save(entry)
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTranspositionTableSize(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	if len(tt.buckets) != 1024*1024/32 {
+		t.Errorf("Bad bucket count for 1MB: %d", len(tt.buckets))
+	}
+	tt.Resize(3)
+	if len(tt.buckets)&(len(tt.buckets)-1) != 0 || len(tt.buckets)*32 > 3*1024*1024 {
+		t.Errorf("Bad bucket count for 3MB: %d", len(tt.buckets))
+	}
+}
+
+func TestTranspositionTableReplacement(t *testing.T) {
+	tt := NewTranspositionTable(1)
+	// keys mapping to the same bucket
+	a, b, c := uint64(5), uint64(5)+tt.mask+1, uint64(5)+2*(tt.mask+1)
+	tt.store(a, 6, t_Exact, 10, newMove(12, 28, m_DoublePawnPush))
+	tt.store(b, 2, t_LowerBound, 20, NullMove)
+	if entry, ok := tt.probe(a); !ok || entry.depth != 6 || entry.score != 10 {
+		t.Errorf("Deeper entry was replaced by a shallower one: %+v", entry)
+	}
+	if entry, ok := tt.probe(b); !ok || entry.bound != t_LowerBound {
+		t.Errorf("Shallower entry not stored in the always replace slot: %+v", entry)
+	}
+	tt.store(c, 1, t_UpperBound, 30, NullMove)
+	if _, ok := tt.probe(b); ok {
+		t.Error("Always replace slot was not replaced")
+	}
+	// a deeper search demotes the previous entry
+	tt.store(b, 8, t_Exact, 40, NullMove)
+	if entry, ok := tt.probe(b); !ok || entry.depth != 8 {
+		t.Errorf("Deeper entry not stored in the depth preferred slot: %+v", entry)
+	}
+	if entry, ok := tt.probe(a); !ok || entry.depth != 6 {
+		t.Errorf("Depth preferred entry not demoted: %+v", entry)
+	}
+	// storing without a best move keeps the previous one
+	tt.store(a, 7, t_UpperBound, 0, NullMove)
+	if entry, _ := tt.probe(a); entry.move != newMove(12, 28, m_DoublePawnPush) {
+		t.Errorf("Hash move lost: %v", entry.move)
+	}
+	tt.Clear()
+	if _, ok := tt.probe(a); ok {
+		t.Error("Entry found after clearing")
+	}
+
+	// empty slots don't match a key of 0, but an entry stored for it does
+	if entry, ok := tt.probe(0); ok {
+		t.Errorf("Empty slot found for the key 0: %+v", entry)
+	}
+	tt.store(0, 3, t_Exact, 0, NullMove)
+	if entry, ok := tt.probe(0); !ok || entry.depth != 3 || entry.bound != t_Exact {
+		t.Errorf("Entry for the key 0 not found: %+v", entry)
+	}
+	tt.store(a, 200, t_UpperBound, 50, NullMove)
+	if entry, ok := tt.probe(a); !ok || entry.depth != math.MaxInt8 || entry.bound != t_UpperBound {
+		t.Errorf("Depth past the entry's range not clamped: %+v", entry)
+	}
+}
+
+// searches every node without pruning or hashing down to the quiescence search, as a reference for the search
+func minimax(s *Searcher, depth int) int {
+	board := s.board
+	if depth == 0 {
+		score := s.quiescence(-scoreInfinity, scoreInfinity)
+		if board.nextColor == c_Black {
+			score = -score
+		}
+		if isMateScore(score) {
+			if score > 0 {
+				return math.MaxInt32
+			}
+			return math.MinInt32
+		}
+		return score
+	}
+	moves := board.GenerateLegalMoves()
+	if len(moves) == 0 {
+		if !board.InCheck() {
+			return 0
+		}
+		if board.nextColor == c_White {
+			return math.MinInt32
+		}
+		return math.MaxInt32
+	}
+	best := math.MaxInt32
+	if board.nextColor == c_White {
+		best = math.MinInt32
+	}
+	for _, move := range moves {
+		board.MakeMove(move)
+		score := minimax(s, depth-1)
+		board.UnmakeMove()
+		if (board.nextColor == c_White && score > best) || (board.nextColor == c_Black && score < best) {
+			best = score
+		}
+	}
+	return best
+}
+
+func TestSearchWithTranspositionTable(t *testing.T) {
+	fens := []string{
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1",
+		"r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1",
+		"8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1",
+	}
+	for _, fen := range fens {
+		var board Board
+		board.LoadFen(fen)
+		plain := minimax(&Searcher{board: &board}, 2)
+		board.SetTranspositionTable(NewTranspositionTable(1))
+		hashed := board.NewRoot(2)
+		if plain != hashed {
+			t.Errorf("\nSearch with transposition table differs for %s\nExpected:%d\n     Got:%d", fen, plain, hashed)
+		}
+		if entry, ok := board.tt.probe(board.zobristHash); !ok || entry.depth != 2 || entry.move == NullMove {
+			t.Errorf("Root not stored in the transposition table for %s: %+v", fen, entry)
+		}
+	}
+}
@@ -1,17 +1,40 @@
 package core
 
 import (
+	"errors"
 	"fmt"
-	"os"
+	"io"
+	"math/bits"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type UCI struct {
 	board     Board
 	useUCI    bool
-	options   map[string]string
+	options   []*uciOption
 	debugMode bool
+	book      *OpeningBook
+
+	// the search runs in its own goroutine so commands are handled while thinking
+	searcher *Searcher
+	// closed when the search goroutine has sent its bestmove
+	searchDone chan struct{}
+	// closed by stop or ponderhit, an infinite or ponder search waits for it before sending its bestmove
+	searchReleased chan struct{}
+
+	out         io.Writer
+	outputMutex sync.Mutex
+}
+
+// Returns an engine in the start position that writes its protocol output to out
+func NewUCI(out io.Writer) *UCI {
+	uci := &UCI{options: newOptions(), out: out}
+	uci.board.LoadFen(startPositionFen)
+	uci.board.SetTranspositionTable(NewTranspositionTable(uci.intOption("Hash")))
+	return uci
 }
 
 func longAlgebraicToMoves(algebraic string) (uint8, uint8) {
@@ -20,79 +43,340 @@ func longAlgebraicToMoves(algebraic string) (uint8, uint8) {
 	return from, to
 }
 
-func removeExcessWhitespace(com string) string {
-	var sb strings.Builder
-	gotSpace := false
-	gotFirstChar := false
-	for _, ch := range com {
-		if ch == ' ' || ch == '\t' {
-			if !gotSpace && gotFirstChar {
-				gotSpace = true
-				sb.WriteRune(' ')
-			} else {
-				gotSpace = false
-				gotFirstChar = true
-				sb.WriteRune(ch)
+// splits a line from the GUI on any run of whitespace, so tabs and the carriage return
+// of CRLF line endings are tolerated
+func tokenize(line string) []string {
+	return strings.Fields(line)
+}
+
+// GUIs don't expect anything but protocol output, so errors are only sent as
+// info strings when debug mode is on
+func (uci *UCI) printError(err string) {
+	if uci.debugMode {
+		uci.returnToGUI("info string " + err)
+	}
+}
+
+// safe to call from the search goroutine
+// buffered writers are flushed after every line, since the GUI waits for it
+func (uci *UCI) returnToGUI(ret string) {
+	uci.outputMutex.Lock()
+	defer uci.outputMutex.Unlock()
+	fmt.Fprintln(uci.out, ret)
+	if flusher, ok := uci.out.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+}
+
+// returns a move from the opening book when OwnBook is enabled, to be played instead of searching
+func (uci *UCI) bookMove() (Move, bool) {
+	if uci.book == nil || !uci.boolOption("OwnBook") {
+		return NullMove, false
+	}
+	return uci.book.PickMove(&uci.board, nil)
+}
+
+// parses the parameters of the go command, which are all optional
+// unknown tokens are skipped as the protocol asks
+func parseSearchLimits(params []string) (SearchLimits, error) {
+	var limits SearchLimits
+	durations := map[string]*time.Duration{
+		"wtime": &limits.WhiteTime, "btime": &limits.BlackTime,
+		"winc": &limits.WhiteIncrement, "binc": &limits.BlackIncrement,
+		"movetime": &limits.MoveTime,
+	}
+	clocks := map[string]*bool{"wtime": &limits.WhiteTimeSet, "btime": &limits.BlackTimeSet}
+	ints := map[string]*int{"movestogo": &limits.MovesToGo, "depth": &limits.Depth, "mate": &limits.Mate}
+	for i := 0; i < len(params); i++ {
+		name := params[i]
+		if name == "infinite" {
+			limits.Infinite = true
+			continue
+		}
+		if name == "ponder" {
+			limits.Ponder = true
+			continue
+		}
+		duration, isDuration := durations[name]
+		number, isInt := ints[name]
+		if !isDuration && !isInt && name != "nodes" {
+			continue
+		}
+		if i+1 >= len(params) {
+			return limits, errors.New("Missing value for " + name)
+		}
+		value, err := strconv.ParseInt(params[i+1], 10, 64)
+		if err != nil {
+			return limits, errors.New("Bad value for " + name + ": " + params[i+1])
+		}
+		i++
+		if isDuration {
+			// the clock can be negative if the engine is already late
+			if value < 0 {
+				value = 0
+			}
+			*duration = time.Duration(value) * time.Millisecond
+			if clock, isClock := clocks[name]; isClock {
+				*clock = true
+			}
+		} else if isInt {
+			*number = int(value)
+		} else {
+			limits.Nodes = uint64(value)
+		}
+	}
+	return limits, nil
+}
+
+// position startpos|fen <fen> [moves <move>...]
+// an illegal move is reported and the moves after it are ignored
+func (uci *UCI) positionCommand(params []string) {
+	if len(params) == 0 {
+		uci.printError("Expected startpos or fen")
+		return
+	}
+	var fen string
+	var moves []string
+	switch params[0] {
+	case "startpos":
+		fen = startPositionFen
+		moves = params[1:]
+	case "fen":
+		end := len(params)
+		for i, param := range params {
+			if param == "moves" {
+				end = i
+				break
 			}
 		}
+		fen = strings.Join(params[1:end], " ")
+		moves = params[end:]
+	default:
+		uci.printError("Bad 1st parameter, expected startpos or fen")
+		return
+	}
+	if len(moves) > 0 && moves[0] != "moves" {
+		uci.printError("Expected moves, got " + moves[0])
+		return
+	}
+	// the position is only replaced if the fen is valid
+	var probe Board
+	if ok, err := probe.LoadFen(fen); !ok {
+		uci.printError(err)
+		return
+	}
+	// positions that can't come from a game would break the search, which expects kings and pawns where they can be
+	if bits.OnesCount64(probe.whiteKing) != 1 || bits.OnesCount64(probe.blackKing) != 1 {
+		uci.printError("Each side needs exactly one king: " + fen)
+		return
+	}
+	them := c_Black - probe.nextColor
+	if probe.IsSquareAttacked(bits.TrailingZeros64(*probe.PieceBBmap[them+p_King]), probe.nextColor) {
+		uci.printError("The side not to move is in check: " + fen)
+		return
+	}
+	if (probe.whitePawns|probe.blackPawns)&(rank1|rank8) != 0 {
+		uci.printError("Pawns on the first or last rank: " + fen)
+		return
+	}
+	uci.stopSearch()
+	uci.board.LoadFen(fen)
+	if len(moves) > 0 {
+		moves = moves[1:]
+	}
+	for _, algebraic := range moves {
+		move, ok := uci.board.ParseMove(algebraic)
+		if !ok {
+			uci.printError("Illegal move " + algebraic + " in " + uci.board.GetFen())
+			return
+		}
+		uci.board.MakeMove(move)
+	}
+}
+
+// scores are sent in centipawns, or in moves for mates with negative values if the engine is getting mated
+func formatScore(score int, bound uint8) string {
+	var ret string
+	if isMateScore(score) {
+		if score > 0 {
+			ret = "mate " + strconv.Itoa((scoreMate-score+1)/2)
+		} else {
+			ret = "mate " + strconv.Itoa(-(scoreMate+score)/2)
+		}
+	} else {
+		ret = "cp " + strconv.Itoa(score)
+	}
+	switch bound {
+	case t_LowerBound:
+		ret += " lowerbound"
+	case t_UpperBound:
+		ret += " upperbound"
+	}
+	return ret
+}
+
+// castling moves in the principal variation are written for the board, see Board.MoveString
+func formatInfo(board *Board, result SearchResult) string {
+	var nps uint64
+	if result.Time > 0 {
+		nps = uint64(float64(result.Nodes) / result.Time.Seconds())
+	}
+	var sb strings.Builder
+	line := result.MultiPV
+	if line == 0 {
+		line = 1
+	}
+	fmt.Fprintf(&sb, "info depth %d seldepth %d multipv %d score %s nodes %d nps %d time %d hashfull %d pv",
+		result.Depth, result.SelDepth, line, formatScore(result.Score, result.Bound), result.Nodes, nps,
+		result.Time.Milliseconds(), result.Hashfull)
+	for _, move := range result.PV {
+		sb.WriteString(" " + board.MoveString(move))
 	}
 	return sb.String()
 }
 
-func printError(err string) {
-	fmt.Println("[Error] " + err)
+func (uci *UCI) searching() bool {
+	if uci.searchDone == nil {
+		return false
+	}
+	select {
+	case <-uci.searchDone:
+		return false
+	default:
+		return true
+	}
 }
 
-func printValidOptions() {
-	// TODO: add some valid options
+func (uci *UCI) goCommand(params []string) {
+	if uci.searching() {
+		uci.printError("Already searching")
+		return
+	}
+	limits, err := parseSearchLimits(params)
+	if err != nil {
+		uci.printError(err.Error())
+		return
+	}
+	waitForRelease := limits.Infinite || limits.Ponder
+	if !waitForRelease {
+		if move, ok := uci.bookMove(); ok {
+			uci.returnToGUI("bestmove " + uci.board.MoveString(move))
+			return
+		}
+	}
+	searcher := NewSearcher(&uci.board)
+	searcher.MultiPV = uci.intOption("MultiPV")
+	searcher.Threads = uci.intOption("Threads")
+	searcher.OnIteration = func(result SearchResult) {
+		uci.returnToGUI(formatInfo(&uci.board, result))
+	}
+	searcher.OnRootMove = func(depth int, move Move, number int) {
+		// GUIs are flooded if the current move is sent from the start
+		if searcher.Elapsed() >= time.Second {
+			uci.returnToGUI(fmt.Sprintf("info depth %d currmove %s currmovenumber %d", depth, uci.board.MoveString(move), number))
+		}
+	}
+	done := make(chan struct{})
+	released := make(chan struct{})
+	uci.searcher, uci.searchDone, uci.searchReleased = searcher, done, released
+	go func() {
+		defer close(done)
+		result := searcher.SearchWithLimits(limits)
+		// the bestmove of an infinite or ponder search is only sent after stop or ponderhit,
+		// even if the search ends earlier
+		if waitForRelease {
+			<-released
+		}
+		bestMove := "bestmove " + uci.board.MoveString(result.BestMove)
+		if len(result.PV) > 1 {
+			bestMove += " ponder " + uci.board.MoveString(result.PV[1])
+		}
+		uci.returnToGUI(bestMove)
+	}()
+}
+
+func (uci *UCI) releaseSearch() {
+	select {
+	case <-uci.searchReleased:
+	default:
+		close(uci.searchReleased)
+	}
+}
+
+// stops the search and waits for its bestmove to be sent
+func (uci *UCI) stopSearch() {
+	if !uci.searching() {
+		return
+	}
+	uci.searcher.Stop()
+	uci.releaseSearch()
+	<-uci.searchDone
 }
 
-func returnToGUI(ret string) {
-	fmt.Println(ret)
+func (uci *UCI) ponderHit() {
+	if !uci.searching() {
+		return
+	}
+	uci.searcher.PonderHit()
+	uci.releaseSearch()
 }
 
-func (uci *UCI) ParseCommand(com string) {
-	com = removeExcessWhitespace(com)
-	split := strings.Split(com, " ")
-	if len(split) < 1 {
-		printError("Command too short")
+// Handles a line sent by the GUI, returns true once the engine should exit
+// Unknown tokens before the command are skipped, so "joho debug on" turns debug mode on
+func (uci *UCI) ParseCommand(line string) bool {
+	tokens := tokenize(line)
+	for i, token := range tokens {
+		if known, quit := uci.runCommand(token, tokens[i+1:]); known {
+			return quit
+		}
+	}
+	if len(tokens) > 0 {
+		uci.printError("Unknown command " + tokens[0])
 	}
-	switch split[0] {
+	return false
+}
+
+// returns whether the command is known and whether the engine should exit
+func (uci *UCI) runCommand(command string, params []string) (known bool, quit bool) {
+	switch command {
 	case "uci":
 		uci.useUCI = true
-		returnToGUI("id name gochess")
-		returnToGUI("id author OFFTKP")
-		printValidOptions()
-		returnToGUI("uciok")
+		uci.returnToGUI("id name gochess")
+		uci.returnToGUI("id author OFFTKP")
+		uci.printOptions()
+		uci.returnToGUI("uciok")
 	case "setoption":
-		if len(split) < 5 {
-			printError("Expected 4 parameters, got " + strconv.Itoa(len(split)-1))
-			return
-		}
-		if split[1] != "name" {
-			printError("Bad 1st parameter, expected 'name'")
-			return
-		}
-		if split[3] != "value" {
-			printError("Bad 3rd parameter, expected 'value'")
-			return
+		if err := uci.setOptionCommand(params); err != nil {
+			uci.printError(err.Error())
 		}
-		uci.options[split[2]] = strings.Join(split[4:], " ")
 	case "debug":
-		if split[1] == "on" {
+		if len(params) == 0 {
+			uci.printError("Expected debug on or off")
+		} else if params[0] == "on" {
 			uci.debugMode = true
-		} else if split[1] == "off" {
+		} else if params[0] == "off" {
 			uci.debugMode = false
 		} else {
-			printError("Bad 1st parameter, expected on/off")
+			uci.printError("Bad 1st parameter, expected on/off")
 		}
+	case "position":
+		uci.positionCommand(params)
+	case "go":
+		uci.goCommand(params)
+	case "stop":
+		uci.stopSearch()
+	case "ponderhit":
+		uci.ponderHit()
 	case "isready":
-		returnToGUI("readyok")
+		uci.returnToGUI("readyok")
 	case "ucinewgame":
+		uci.stopSearch()
 		uci.board.Reset()
 	case "quit":
-		os.Exit(0)
+		uci.stopSearch()
+		return true, true
 	default:
-		printError("Unknown command:" + split[0])
+		return false, false
 	}
+	return true, false
 }
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Options advertised to the GUI in the uci response and changed with setoption
+
+const (
+	o_Check = iota
+	o_Spin
+	o_Combo
+	o_Button
+	o_String
+)
+
+var optionTypeNames = [...]string{"check", "spin", "combo", "button", "string"}
+
+type uciOption struct {
+	name         string
+	kind         int
+	defaultValue string
+	// range of spin options
+	min, max int
+	// values of combo options
+	vars  []string
+	value string
+	// applies a validated value, nil if the value is only read when needed
+	apply func(uci *UCI, value string) error
+}
+
+func newOptions() []*uciOption {
+	options := []*uciOption{
+		{name: "Hash", kind: o_Spin, defaultValue: strconv.Itoa(DefaultHashSizeMB), min: 1, max: 65536,
+			apply: func(uci *UCI, value string) error {
+				size, _ := strconv.Atoi(value)
+				uci.board.SetTranspositionTable(NewTranspositionTable(size))
+				return nil
+			}},
+		{name: "Clear Hash", kind: o_Button,
+			apply: func(uci *UCI, value string) error {
+				uci.board.tt.Clear()
+				return nil
+			}},
+		{name: "Threads", kind: o_Spin, defaultValue: "1", min: 1, max: 256},
+		{name: "MultiPV", kind: o_Spin, defaultValue: "1", min: 1, max: 256},
+		{name: "Ponder", kind: o_Check, defaultValue: "false"},
+		{name: "OwnBook", kind: o_Check, defaultValue: "false"},
+		{name: "BookFile", kind: o_String, defaultValue: "",
+			apply: func(uci *UCI, value string) error {
+				if value == "" {
+					uci.book = nil
+					return nil
+				}
+				book, err := LoadPolyglotBook(value)
+				if err != nil {
+					return errors.New("Could not load book: " + err.Error())
+				}
+				uci.book = book
+				return nil
+			}},
+		{name: "UCI_Chess960", kind: o_Check, defaultValue: "false",
+			apply: func(uci *UCI, value string) error {
+				uci.board.SetChess960(value == "true")
+				return nil
+			}},
+	}
+	for _, option := range options {
+		option.value = option.defaultValue
+	}
+	return options
+}
+
+// returns the line advertising the option, as in "option name Hash type spin default 16 min 1 max 65536"
+func (option *uciOption) String() string {
+	var sb strings.Builder
+	sb.WriteString("option name " + option.name + " type " + optionTypeNames[option.kind])
+	switch option.kind {
+	case o_Check, o_Combo:
+		sb.WriteString(" default " + option.defaultValue)
+	case o_Spin:
+		sb.WriteString(" default " + option.defaultValue + " min " + strconv.Itoa(option.min) + " max " + strconv.Itoa(option.max))
+	case o_String:
+		if option.defaultValue == "" {
+			sb.WriteString(" default <empty>")
+		} else {
+			sb.WriteString(" default " + option.defaultValue)
+		}
+	}
+	for _, v := range option.vars {
+		sb.WriteString(" var " + v)
+	}
+	return sb.String()
+}
+
+// returns the value in its canonical form, or an error if it isn't valid for the option
+func (option *uciOption) validate(value string) (string, error) {
+	switch option.kind {
+	case o_Check:
+		value = strings.ToLower(value)
+		if value != "true" && value != "false" {
+			return "", errors.New("Expected true or false for " + option.name + ", got " + value)
+		}
+	case o_Spin:
+		number, err := strconv.Atoi(value)
+		if err != nil || number < option.min || number > option.max {
+			return "", errors.New("Expected a number between " + strconv.Itoa(option.min) + " and " +
+				strconv.Itoa(option.max) + " for " + option.name + ", got " + value)
+		}
+		value = strconv.Itoa(number)
+	case o_Combo:
+		for _, v := range option.vars {
+			if strings.EqualFold(v, value) {
+				return v, nil
+			}
+		}
+		return "", errors.New("Expected one of " + strings.Join(option.vars, ", ") + " for " + option.name + ", got " + value)
+	case o_String:
+		if value == "<empty>" {
+			value = ""
+		}
+	}
+	return value, nil
+}
+
+// option names are case insensitive
+func (uci *UCI) option(name string) *uciOption {
+	for _, option := range uci.options {
+		if strings.EqualFold(option.name, name) {
+			return option
+		}
+	}
+	return nil
+}
+
+func (uci *UCI) intOption(name string) int {
+	value, _ := strconv.Atoi(uci.option(name).value)
+	return value
+}
+
+func (uci *UCI) boolOption(name string) bool {
+	return uci.option(name).value == "true"
+}
+
+func (uci *UCI) printOptions() {
+	for _, option := range uci.options {
+		uci.returnToGUI(option.String())
+	}
+}
+
+// setoption name <id> [value <x>], both the name and the value may contain spaces
+func (uci *UCI) setOptionCommand(params []string) error {
+	if len(params) < 2 || params[0] != "name" {
+		return errors.New("Expected setoption name <id> [value <x>]")
+	}
+	end := len(params)
+	for i, param := range params {
+		if param == "value" {
+			end = i
+			break
+		}
+	}
+	name := strings.Join(params[1:end], " ")
+	value := ""
+	if end < len(params) {
+		value = strings.Join(params[end+1:], " ")
+	}
+	option := uci.option(name)
+	if option == nil {
+		return errors.New("Unknown option " + name)
+	}
+	if option.kind == o_Button {
+		uci.stopSearch()
+		return option.apply(uci, "")
+	}
+	if end == len(params) {
+		return errors.New("Missing value for " + option.name)
+	}
+	value, err := option.validate(value)
+	if err != nil {
+		return err
+	}
+	uci.stopSearch()
+	if option.apply != nil {
+		if err := option.apply(uci, value); err != nil {
+			return err
+		}
+	}
+	option.value = value
+	return nil
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOptionAdvertisement(t *testing.T) {
+	var output bytes.Buffer
+	uci := NewUCI(&output)
+	uci.printOptions()
+	expected := []string{
+		"option name Hash type spin default 16 min 1 max 65536",
+		"option name Clear Hash type button",
+		"option name Threads type spin default 1 min 1 max 256",
+		"option name MultiPV type spin default 1 min 1 max 256",
+		"option name Ponder type check default false",
+		"option name OwnBook type check default false",
+		"option name BookFile type string default <empty>",
+		"option name UCI_Chess960 type check default false",
+	}
+	if got := strings.Split(strings.TrimSpace(output.String()), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("\nBad options\nExpected:%q\n     Got:%q", expected, got)
+	}
+	combo := uciOption{name: "Style", kind: o_Combo, defaultValue: "Normal", vars: []string{"Solid", "Normal", "Risky"}}
+	if line := combo.String(); line != "option name Style type combo default Normal var Solid var Normal var Risky" {
+		t.Errorf("Bad combo option: %s", line)
+	}
+	if value, err := combo.validate("risky"); err != nil || value != "Risky" {
+		t.Errorf("Combo value not validated: %s %v", value, err)
+	}
+	if _, err := combo.validate("Wild"); err == nil {
+		t.Error("Unknown combo value accepted")
+	}
+}
+
+func TestSetOption(t *testing.T) {
+	type testCase struct {
+		command string
+		valid   bool
+		option  string
+		value   string
+	}
+	testCases := []testCase{
+		{"name Hash value 1", true, "Hash", "1"},
+		{"name hash value 2", true, "Hash", "2"},
+		{"name Hash value 0", false, "Hash", "16"},
+		{"name Hash value lots", false, "Hash", "16"},
+		{"name Hash", false, "Hash", "16"},
+		{"name MultiPV value 3", true, "MultiPV", "3"},
+		{"name Threads value 4", true, "Threads", "4"},
+		{"name Ponder value TRUE", true, "Ponder", "true"},
+		{"name Ponder value yes", false, "Ponder", "false"},
+		{"name Clear Hash", true, "Clear Hash", ""},
+		{"name BookFile value <empty>", true, "BookFile", ""},
+		{"name BookFile value /nonexistent/book file.bin", false, "BookFile", ""},
+		{"name Unknown Option value 1", false, "Hash", "16"},
+		{"value 1", false, "Hash", "16"},
+	}
+	for _, test := range testCases {
+		uci := NewUCI(&bytes.Buffer{})
+		err := uci.setOptionCommand(strings.Fields(test.command))
+		if (err == nil) != test.valid {
+			t.Errorf("setoption %s: expected valid:%t, got %v", test.command, test.valid, err)
+		}
+		if value := uci.option(test.option).value; value != test.value {
+			t.Errorf("setoption %s: expected %s to be %q, got %q", test.command, test.option, test.value, value)
+		}
+	}
+
+	uci := NewUCI(&bytes.Buffer{})
+	uci.setOptionCommand(strings.Fields("name Hash value 1"))
+	if len(uci.board.tt.buckets)*32 != 1024*1024 {
+		t.Errorf("Hash not resized: %d buckets", len(uci.board.tt.buckets))
+	}
+	uci.board.tt.store(1, 5, t_Exact, 10, NullMove)
+	uci.setOptionCommand(strings.Fields("name Clear Hash"))
+	if _, ok := uci.board.tt.probe(1); ok {
+		t.Error("Hash not cleared")
+	}
+}
@@ -0,0 +1,259 @@
+package core
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns what the engine sent so far, synchronized with the search goroutine
+func sentToGUI(uci *UCI, output *bytes.Buffer) string {
+	uci.outputMutex.Lock()
+	defer uci.outputMutex.Unlock()
+	return output.String()
+}
+
+func waitForSearch(t *testing.T, uci *UCI) {
+	select {
+	case <-uci.searchDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Search didn't finish")
+	}
+}
+
+func TestUCIInfiniteSearch(t *testing.T) {
+	var output bytes.Buffer
+	uci := NewUCI(&output)
+	uci.goCommand([]string{"infinite"})
+	time.Sleep(50 * time.Millisecond)
+	if !uci.searching() || strings.Contains(sentToGUI(uci, &output), "bestmove") {
+		t.Fatalf("Infinite search ended before stop: %q", sentToGUI(uci, &output))
+	}
+	// a second go while searching is rejected
+	uci.goCommand([]string{"depth", "1"})
+	uci.stopSearch()
+	if uci.searching() || strings.Count(sentToGUI(uci, &output), "bestmove ") != 1 {
+		t.Errorf("Expected a single bestmove after stop, got %q", sentToGUI(uci, &output))
+	}
+	uci.stopSearch()
+}
+
+func TestUCIPonder(t *testing.T) {
+	var output bytes.Buffer
+	uci := NewUCI(&output)
+	uci.goCommand([]string{"ponder", "movetime", "50"})
+	time.Sleep(100 * time.Millisecond)
+	if strings.Contains(sentToGUI(uci, &output), "bestmove") {
+		t.Fatalf("Ponder search used its time before the ponderhit: %q", sentToGUI(uci, &output))
+	}
+	uci.ponderHit()
+	waitForSearch(t, uci)
+	if !strings.Contains(sentToGUI(uci, &output), "\nbestmove ") {
+		t.Errorf("No bestmove after ponderhit: %q", sentToGUI(uci, &output))
+	}
+}
+
+func TestUCISearch(t *testing.T) {
+	var output bytes.Buffer
+	uci := NewUCI(&output)
+	uci.goCommand([]string{"depth", "3"})
+	waitForSearch(t, uci)
+	lines := strings.Split(strings.TrimSpace(sentToGUI(uci, &output)), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[3], "bestmove ") {
+		t.Fatalf("Expected 3 info lines and a bestmove, got %q", lines)
+	}
+	for i, line := range lines[:3] {
+		if !strings.HasPrefix(line, "info depth "+strconv.Itoa(i+1)+" seldepth ") || !strings.Contains(line, " pv ") {
+			t.Errorf("Bad info line %q", line)
+		}
+	}
+}
+
+func TestParseSearchLimits(t *testing.T) {
+	ms := time.Millisecond
+	type testCase struct {
+		params   []string
+		expected SearchLimits
+		valid    bool
+	}
+	testCases := []testCase{
+		{[]string{}, SearchLimits{}, true},
+		{[]string{"infinite"}, SearchLimits{Infinite: true}, true},
+		{[]string{"ponder", "wtime", "1000"}, SearchLimits{Ponder: true, WhiteTime: 1000 * ms, WhiteTimeSet: true}, true},
+		{[]string{"wtime", "300000", "btime", "290000", "winc", "2000", "binc", "1000", "movestogo", "20"},
+			SearchLimits{WhiteTime: 300000 * ms, BlackTime: 290000 * ms, WhiteTimeSet: true, BlackTimeSet: true,
+				WhiteIncrement: 2000 * ms, BlackIncrement: 1000 * ms, MovesToGo: 20}, true},
+		{[]string{"depth", "6", "nodes", "100000"}, SearchLimits{Depth: 6, Nodes: 100000}, true},
+		{[]string{"movetime", "500"}, SearchLimits{MoveTime: 500 * ms}, true},
+		{[]string{"mate", "3"}, SearchLimits{Mate: 3}, true},
+		// a flagged clock counts as no time left, but still as a clock
+		{[]string{"wtime", "-20", "btime", "1000"}, SearchLimits{BlackTime: 1000 * ms, WhiteTimeSet: true, BlackTimeSet: true}, true},
+		{[]string{"wtime", "0"}, SearchLimits{WhiteTimeSet: true}, true},
+		{[]string{"depth"}, SearchLimits{}, false},
+		{[]string{"depth", "six"}, SearchLimits{}, false},
+		// unknown tokens are skipped
+		{[]string{"speed", "1"}, SearchLimits{}, true},
+		{[]string{"searchmoves", "e2e4", "depth", "3"}, SearchLimits{Depth: 3}, true},
+	}
+	for _, test := range testCases {
+		limits, err := parseSearchLimits(test.params)
+		if (err == nil) != test.valid || (test.valid && limits != test.expected) {
+			t.Errorf("\nBad go parameters %v\nExpected:%+v\n     Got:%+v %v", test.params, test.expected, limits, err)
+		}
+	}
+}
+
+// feeds whole sessions through ParseCommand and checks everything the engine sends back
+func TestUCITranscripts(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    []string
+		expected []string
+	}
+	testCases := []testCase{
+		{"isready", []string{"isready"}, []string{"readyok"}},
+		{"crlf and tabs", []string{"isready\r", "\t  isready \t\r"}, []string{"readyok", "readyok"}},
+		{"empty lines", []string{"", "   ", "\r", "isready"}, []string{"readyok"}},
+		{"tokens before the command", []string{"joho isready"}, []string{"readyok"}},
+		{"errors are silent without debug", []string{"foo bar", "debug", "debug maybe", "position nowhere",
+			"go depth six", "setoption name Nope value 1", "isready"}, []string{"readyok"}},
+		{"errors in debug mode", []string{"joho debug on", "foo bar", "debug", "position nowhere",
+			"position startpos moves e2e5", "go depth six", "setoption name Nope value 1", "debug off", "foo"},
+			[]string{
+				"info string Unknown command foo",
+				"info string Expected debug on or off",
+				"info string Bad 1st parameter, expected startpos or fen",
+				"info string Illegal move e2e5 in " + startPositionFen,
+				"info string Bad value for depth: six",
+				"info string Unknown option Nope",
+			}},
+		{"setoption with crlf", []string{"debug on", "setoption name Threads value 2\r", "setoption\tname MultiPV\tvalue 3"}, []string{}},
+		{"quit", []string{"quit", "isready"}, []string{}},
+	}
+	for _, test := range testCases {
+		var output bytes.Buffer
+		uci := NewUCI(&output)
+		for _, line := range test.input {
+			if uci.ParseCommand(line) {
+				break
+			}
+		}
+		expected := strings.Join(test.expected, "\n")
+		if len(test.expected) > 0 {
+			expected += "\n"
+		}
+		if got := sentToGUI(uci, &output); got != expected {
+			t.Errorf("\nTranscript %s\nExpected:%q\n     Got:%q", test.name, expected, got)
+		}
+	}
+
+	// the uci handshake ends with uciok after the id and options
+	var output bytes.Buffer
+	uci := NewUCI(&output)
+	uci.ParseCommand("uci\r")
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if lines[0] != "id name gochess" || lines[len(lines)-1] != "uciok" {
+		t.Errorf("Bad uci response %q", output.String())
+	}
+	if uci.intOption("Threads") != 1 {
+		t.Error("Options changed by the uci command")
+	}
+	uci.ParseCommand("setoption name Threads value 2\r")
+	uci.ParseCommand("setoption\tname MultiPV\tvalue 3")
+	if uci.intOption("Threads") != 2 || uci.intOption("MultiPV") != 3 {
+		t.Errorf("Options not set: Threads %d MultiPV %d", uci.intOption("Threads"), uci.intOption("MultiPV"))
+	}
+}
+
+func TestUCIPosition(t *testing.T) {
+	type testCase struct {
+		params   string
+		expected string
+	}
+	testCases := []testCase{
+		{"startpos", startPositionFen},
+		{"startpos moves e2e4 e7e5 g1f3", "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b KQkq - 1 2"},
+		{"fen 8/1P6/8/8/8/8/8/k3K3 w - - 0 1 moves b7b8q", "1Q6/8/8/8/8/8/8/k3K3 b - - 0 1"},
+		{"fen 8/1P6/8/8/8/8/8/k3K3 w - - 0 1 moves b7b8N", "1N6/8/8/8/8/8/8/k3K3 b - - 0 1"},
+		{"fen r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1 moves e1g1 e8c8", "2kr3r/8/8/8/8/8/8/R4RK1 w - - 2 2"},
+		// the moves after an illegal one are ignored
+		{"startpos moves e2e4 e2e4 e7e5", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+		{"startpos moves e2e4 e7", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1"},
+	}
+	for _, test := range testCases {
+		uci := NewUCI(&bytes.Buffer{})
+		uci.positionCommand(strings.Fields(test.params))
+		if fen := uci.board.GetFen(); fen != test.expected {
+			t.Errorf("\nBad position after %s\nExpected:%s\n     Got:%s", test.params, test.expected, fen)
+		}
+	}
+
+	// a bad fen leaves the position unchanged and is reported in debug mode
+	var out bytes.Buffer
+	uci := NewUCI(&out)
+	uci.ParseCommand("debug on")
+	uci.positionCommand(strings.Fields("startpos moves d2d4"))
+	for _, fen := range []string{
+		"8/8/8 w - - 0 1",
+		"8/8/8/8/8/8/8/8 w - - 0 1",
+		// the king of the side not to move could be captured
+		"4k3/4R3/8/8/8/8/8/4K3 w - - 0 1",
+		"P3k3/8/8/8/8/8/8/4K3 w - - 0 1",
+		"4k3/8/8/8/8/8/8/p3K3 b - - 0 1",
+	} {
+		out.Reset()
+		uci.positionCommand(append([]string{"fen"}, strings.Fields(fen)...))
+		if !strings.HasPrefix(out.String(), "info string ") {
+			t.Errorf("Bad fen %s not reported: %q", fen, out.String())
+		}
+		if len(uci.board.history) != 1 || uci.board.GetFen() != "rnbqkbnr/pppppppp/8/8/3P4/8/PPP1PPPP/RNBQKBNR b KQkq d3 0 1" {
+			t.Errorf("Position changed by the bad fen %s: %s", fen, uci.board.GetFen())
+		}
+	}
+	uci.ParseCommand("debug off")
+	// in Chess960 the king takes its own rook to castle, also in the search output
+	uci.ParseCommand("setoption name UCI_Chess960 value true")
+	uci.ParseCommand("position fen 1r2k1r1/pppppppp/8/8/8/8/PPPPPPPP/1R2K1R1 w GBgb - 0 1 moves e1g1 e8b8")
+	if fen := uci.board.GetFen(); fen != "2kr2r1/pppppppp/8/8/8/8/PPPPPPPP/1R3RK1 w - - 2 2" {
+		t.Errorf("Bad Chess960 castling: %s", fen)
+	}
+	uci.ParseCommand("position fen 4k3/8/8/8/8/8/8/R3K2R w KQ - 0 1")
+	move, _ := uci.board.ParseMove("e1h1")
+	if info := formatInfo(&uci.board, SearchResult{PV: []Move{move}}); !strings.HasSuffix(info, " pv e1h1") {
+		t.Errorf("Castling not written as the king taking the rook: %s", info)
+	}
+	uci.ParseCommand("setoption name UCI_Chess960 value false")
+
+	// the played moves are kept for repetition detection
+	uci.positionCommand(strings.Fields("startpos moves g1f3 g8f6 f3g1 f6g8"))
+	if len(uci.board.history) != 4 || uci.board.history[0].zobristHash != uci.board.zobristHash {
+		t.Errorf("Game history not recorded: %d moves", len(uci.board.history))
+	}
+}
+
+func TestFormatInfo(t *testing.T) {
+	type testCase struct {
+		result   SearchResult
+		expected string
+	}
+	pv := []Move{newMove(12, 28, m_DoublePawnPush), newMove(52, 36, m_DoublePawnPush)}
+	testCases := []testCase{
+		{SearchResult{Depth: 5, SelDepth: 9, Score: 35, PV: pv, Nodes: 20000, Time: 100 * time.Millisecond, Hashfull: 12},
+			"info depth 5 seldepth 9 multipv 1 score cp 35 nodes 20000 nps 200000 time 100 hashfull 12 pv e2e4 e7e5"},
+		{SearchResult{Depth: 3, SelDepth: 3, Score: scoreMate - 3, PV: pv[:1], Nodes: 10},
+			"info depth 3 seldepth 3 multipv 1 score mate 2 nodes 10 nps 0 time 0 hashfull 0 pv e2e4"},
+		{SearchResult{Depth: 4, SelDepth: 6, Score: -scoreMate + 2, Bound: t_LowerBound, PV: pv, Nodes: 10, Time: time.Second},
+			"info depth 4 seldepth 6 multipv 1 score mate -1 lowerbound nodes 10 nps 10 time 1000 hashfull 0 pv e2e4 e7e5"},
+		{SearchResult{Depth: 7, SelDepth: 12, Score: -20, Bound: t_UpperBound, PV: pv[:1], Nodes: 10, Time: time.Second},
+			"info depth 7 seldepth 12 multipv 1 score cp -20 upperbound nodes 10 nps 10 time 1000 hashfull 0 pv e2e4"},
+	}
+	var board Board
+	board.LoadFen(startPositionFen)
+	for _, test := range testCases {
+		if info := formatInfo(&board, test.result); info != test.expected {
+			t.Errorf("\nBad info line\nExpected:%s\n     Got:%s", test.expected, info)
+		}
+	}
+}
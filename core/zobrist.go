@@ -0,0 +1,44 @@
+package core
+
+// Zobrist keys shared by every board, https://www.chessprogramming.org/Zobrist_Hashing
+// They are generated from a fixed seed so that a position hashes the same across boards and processes,
+// which allows hashes to be persisted. Changing the seed or the generation order changes every hash.
+
+const zobristSeed uint64 = 0x2545f4914f6cdd1d
+
+var pieceHashMap [12][64]uint64
+var nextColorHashMap [7]uint64 // 0 - white, 6 - black
+var castlingHashMap [16]uint64 // 0000 KQkq bits for speed
+var enPassantHashMap [8]uint64 // to indicate the file of the en passant square
+
+func generateZobrist() {
+	rng := xorshiftRandom(zobristSeed)
+	for piece := 0; piece < 12; piece++ {
+		for square := 0; square < 64; square++ {
+			pieceHashMap[piece][square] = rng.next()
+		}
+	}
+	nextColorHashMap[c_White] = rng.next()
+	nextColorHashMap[c_Black] = rng.next()
+	for i := 0; i < 16; i++ {
+		castlingHashMap[i] = rng.next()
+	}
+	for i := 0; i < 8; i++ {
+		enPassantHashMap[i] = rng.next()
+	}
+}
+
+// xorshift64* generator, https://www.chessprogramming.org/Pseudo-Random_Number_Generator
+type xorshiftRandom uint64
+
+func (r *xorshiftRandom) next() uint64 {
+	*r ^= *r >> 12
+	*r ^= *r << 25
+	*r ^= *r >> 27
+	return uint64(*r) * 2685821657736338717
+}
+
+// random numbers with few set bits, used to search for magics
+func (r *xorshiftRandom) sparse() uint64 {
+	return r.next() & r.next() & r.next()
+}